@@ -1,14 +1,16 @@
 package amongus
 
 import (
+	"encoding/json"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/utils/pkg/game"
-	"log"
+	"sort"
 	"strings"
 )
 
 type GameData struct {
-	//indexed by amongusname
-	PlayerData map[string]PlayerData `json:"playerData"`
+	//indexed by color; in-game names aren't unique within a lobby, but colors are
+	PlayerData map[int]PlayerData `json:"players"`
 
 	Phase  game.Phase   `json:"phase"`
 	Room   string       `json:"room"`
@@ -16,9 +18,32 @@ type GameData struct {
 	Map    game.PlayMap `json:"map"`
 }
 
+// UnmarshalJSON also reads game data saved before players were keyed by color, so games in progress during an update
+// keep their players
+func (auData *GameData) UnmarshalJSON(data []byte) error {
+	type gameData GameData
+	aux := struct {
+		*gameData
+		// LegacyPlayerData was indexed by in-game name
+		LegacyPlayerData map[string]PlayerData `json:"playerData"`
+	}{gameData: (*gameData)(auData)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if auData.PlayerData == nil {
+		auData.PlayerData = map[int]PlayerData{}
+	}
+	for _, playerData := range aux.LegacyPlayerData {
+		if _, ok := auData.PlayerData[playerData.Color]; !ok {
+			auData.PlayerData[playerData.Color] = playerData
+		}
+	}
+	return nil
+}
+
 func NewGameData() GameData {
 	return GameData{
-		PlayerData: map[string]PlayerData{},
+		PlayerData: map[int]PlayerData{},
 		Phase:      game.MENU,
 		Room:       "",
 		Region:     "",
@@ -90,53 +115,112 @@ func (auData *GameData) GetPlayMap() game.PlayMap {
 	return auData.Map
 }
 
-func (auData *GameData) ClearPlayerData(name string) {
-	delete(auData.PlayerData, name)
+func (auData *GameData) ClearPlayerData(color int) {
+	delete(auData.PlayerData, color)
+}
+
+// MovePlayer moves the player named name to a new color, returning the color they had. If several players have the
+// name, we can't tell which of them changed color, so none are moved
+func (auData *GameData) MovePlayer(name string, color int) (oldColor int, moved bool) {
+	oldColor = -1
+	for c, playerData := range auData.PlayerData {
+		if playerData.Name == name && c != color {
+			if oldColor != -1 {
+				return -1, false
+			}
+			oldColor = c
+		}
+	}
+	if oldColor == -1 {
+		return -1, false
+	}
+	playerData := auData.PlayerData[oldColor]
+	playerData.Color = color
+	delete(auData.PlayerData, oldColor)
+	auData.PlayerData[color] = playerData
+	return oldColor, true
 }
 
 func (auData *GameData) applyPlayerUpdate(update game.Player) (bool, bool, PlayerData) {
-	if _, ok := auData.PlayerData[update.Name]; !ok {
-		auData.PlayerData[update.Name] = PlayerData{
+	if _, ok := auData.PlayerData[update.Color]; !ok {
+		auData.PlayerData[update.Color] = PlayerData{
 			Color:   update.Color,
 			Name:    update.Name,
 			IsAlive: !update.IsDead,
 		}
-		log.Printf("Added new player instance for %s\n", update.Name)
-		return true, false, auData.PlayerData[update.Name]
+		logging.Default().Debug("Added new player instance", "name", update.Name)
+		return true, false, auData.PlayerData[update.Color]
 	}
-	playerData := auData.PlayerData[update.Name]
+	playerData := auData.PlayerData[update.Color]
 	isUpdate := playerData.isDifferent(update)
-	isAliveUpdate := auData.PlayerData[update.Name].IsAlive != !update.IsDead
+	isAliveUpdate := auData.PlayerData[update.Color].IsAlive != !update.IsDead
 	if isUpdate {
 		p := PlayerData{
 			Color:   update.Color,
 			Name:    update.Name,
 			IsAlive: !update.IsDead,
 		}
-		auData.PlayerData[update.Name] = p
+		auData.PlayerData[update.Color] = p
 	}
 
-	return isUpdate, isAliveUpdate, auData.PlayerData[update.Name]
+	return isUpdate, isAliveUpdate, auData.PlayerData[update.Color]
 }
 
 func (auData *GameData) GetByColor(text string) (PlayerData, bool) {
 	text = strings.ToLower(text)
 
-	for _, playerData := range auData.PlayerData {
-		if game.GetColorStringForInt(playerData.Color) == text {
+	if color, ok := game.ColorStrings[text]; ok {
+		if playerData, ok := auData.PlayerData[color]; ok {
 			return playerData, true
 		}
 	}
 	return UnlinkedPlayer, false
 }
 
-func (auData *GameData) GetByName(text string) (PlayerData, bool) {
-	text = strings.ToLower(text)
+// GetPlayer returns the player with the color, as long as they still have the name they were linked by
+func (auData *GameData) GetPlayer(name string, color int) (PlayerData, bool) {
+	if playerData, ok := auData.PlayerData[color]; ok && playerData.Name == name {
+		return playerData, true
+	}
+	return UnlinkedPlayer, false
+}
 
+// GetByName returns the player with the name, unless there's none or more than one
+func (auData *GameData) GetByName(name string) (PlayerData, bool) {
+	if auData.IsDuplicateName(name) {
+		return UnlinkedPlayer, false
+	}
 	for _, playerData := range auData.PlayerData {
-		if strings.ReplaceAll(strings.ToLower(playerData.Name), " ", "") == strings.ReplaceAll(strings.ToLower(text), " ", "") {
+		if playerData.Name == name {
 			return playerData, true
 		}
 	}
 	return UnlinkedPlayer, false
 }
+
+// IsDuplicateName returns true if more than one player in the lobby has the name
+func (auData *GameData) IsDuplicateName(name string) bool {
+	count := 0
+	for _, playerData := range auData.PlayerData {
+		if playerData.Name == name {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// DuplicateNames returns the names that more than one player in the lobby has, sorted
+func (auData *GameData) DuplicateNames() []string {
+	counts := make(map[string]int)
+	for _, playerData := range auData.PlayerData {
+		counts[playerData.Name]++
+	}
+	var names []string
+	for name, count := range counts {
+		if count > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,7 @@
 package amongus
 
 import (
+	"encoding/json"
 	"github.com/automuteus/utils/pkg/game"
 	"testing"
 )
@@ -10,7 +11,7 @@ func TestGameData_UpdatePhase(t *testing.T) {
 	if old := gd.UpdatePhase(game.MENU); old != game.MENU || gd.Phase != game.MENU {
 		t.Error("Expected MENU->MENU transition to be a no-op")
 	}
-	gd.PlayerData["name"] = PlayerData{
+	gd.PlayerData[game.Red] = PlayerData{
 		Color:   game.Red,
 		Name:    "name",
 		IsAlive: false,
@@ -25,7 +26,7 @@ func TestGameData_UpdatePhase(t *testing.T) {
 
 	gd.SetRoomRegionMap("A", "US", game.SKELD)
 
-	if !gd.PlayerData["name"].IsAlive {
+	if !gd.PlayerData[game.Red].IsAlive {
 		t.Error("Player was not set as alive when transitioning from LOBBY->TASKS (game started)")
 	}
 
@@ -36,3 +37,59 @@ func TestGameData_UpdatePhase(t *testing.T) {
 		t.Error("GameData was not reset properly when transitioning from TASKS->MENU")
 	}
 }
+
+func TestGameData_DuplicateNames(t *testing.T) {
+	gd := NewGameData()
+	gd.UpdatePhase(game.LOBBY)
+	gd.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Player", Color: game.Red})
+	gd.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Player", Color: game.Blue})
+	gd.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Bob", Color: game.Green})
+
+	if gd.GetNumDetectedPlayers() != 3 {
+		t.Errorf("Expected players with the same name to be tracked separately, got %d players", gd.GetNumDetectedPlayers())
+	}
+	if names := gd.DuplicateNames(); len(names) != 1 || names[0] != "Player" {
+		t.Errorf("Expected Player to be the only duplicate name, got %v", names)
+	}
+	if !gd.IsDuplicateName("Player") || gd.IsDuplicateName("Bob") {
+		t.Error("Expected only Player to be a duplicate name")
+	}
+
+	if _, moved := gd.MovePlayer("Player", game.Pink); moved {
+		t.Error("Expected a player with a duplicate name not to be moved, since we can't tell which one it is")
+	}
+	if old, moved := gd.MovePlayer("Bob", game.Pink); !moved || old != game.Green {
+		t.Error("Expected Bob to be moved from green")
+	}
+	if _, found := gd.GetPlayer("Bob", game.Pink); !found {
+		t.Error("Expected Bob to be found at their new color")
+	}
+	if _, found := gd.GetPlayer("Bob", game.Green); found {
+		t.Error("Expected Bob not to be found at their old color")
+	}
+
+	gd.ClearPlayerData(game.Red)
+	if gd.IsDuplicateName("Player") {
+		t.Error("Expected Player not to be a duplicate name once one of them left")
+	}
+}
+
+func TestGameData_UnmarshalLegacy(t *testing.T) {
+	var gd GameData
+	err := json.Unmarshal([]byte(`{"playerData":{"bob":{"color":2,"name":"bob","isAlive":true}},"phase":1}`), &gd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := gd.PlayerData[2]; !ok || p.Name != "bob" || gd.Phase != game.TASKS {
+		t.Errorf("Expected players keyed by name to be keyed by color, got %+v", gd)
+	}
+
+	gd = GameData{}
+	err = json.Unmarshal([]byte(`{"players":{"3":{"color":3,"name":"alice","isAlive":true}}}`), &gd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := gd.GetByName("alice"); !ok || p.Color != 3 {
+		t.Errorf("Expected players keyed by color to be read, got %+v", gd)
+	}
+}
@@ -0,0 +1,159 @@
+package amongus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxInGameNameLength is the longest name Among Us allows; longer Discord names have to be truncated in-game
+const MaxInGameNameLength = 10
+
+const (
+	// HighConfidenceMatch and above links the player automatically; only exact and truncated names score that high
+	HighConfidenceMatch = 0.95
+	// MediumConfidenceMatch and above asks the user to confirm they're the player
+	MediumConfidenceMatch = 0.7
+	// maxFuzzyScore caps the names that are only close (a typo, or a prefix), so they're always confirmed first
+	maxFuzzyScore = 0.9
+	// minPrefixLength is the shortest normalized name we consider as a prefix of a longer name; "a" prefixes too much
+	minPrefixLength = 3
+)
+
+var stripDiacritics = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeName lowercases a name and strips diacritics, leaving only letters and digits (no spaces, emoji or symbols)
+func NormalizeName(name string) string {
+	stripped, _, err := transform.String(stripDiacritics, name)
+	if err != nil {
+		stripped = name
+	}
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(stripped) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NameMatchScore returns how confident (0 to 1) we are that the player named inGameName is the Discord user with
+// the username or nickname discordName
+func NameMatchScore(inGameName, discordName string) float64 {
+	player := NormalizeName(inGameName)
+	user := NormalizeName(discordName)
+	if player == "" || user == "" {
+		return 0
+	}
+	if player == user {
+		return 1
+	}
+	playerLen := utf8.RuneCountInString(player)
+	userLen := utf8.RuneCountInString(user)
+
+	best := 1 - float64(levenshtein([]rune(player), []rune(user)))/float64(maxInt(playerLen, userLen))
+	if playerLen >= minPrefixLength && strings.HasPrefix(user, player) {
+		// a name that fills the in-game limit was almost certainly truncated from the longer Discord name
+		if utf8.RuneCountInString(inGameName) >= MaxInGameNameLength {
+			return HighConfidenceMatch
+		}
+		best = maxFloat(best, 0.6+0.3*float64(playerLen)/float64(userLen))
+	} else if userLen >= minPrefixLength && strings.HasPrefix(player, user) {
+		best = maxFloat(best, 0.6+0.3*float64(userLen)/float64(playerLen))
+	}
+	return minFloat(best, maxFuzzyScore)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type NameMatchOutcome string
+
+const (
+	NameMatchLinked    NameMatchOutcome = "linked"
+	NameMatchPrompted  NameMatchOutcome = "prompted"
+	NameMatchAmbiguous NameMatchOutcome = "ambiguous"
+	NameMatchNone      NameMatchOutcome = "none"
+	NameMatchConfirmed NameMatchOutcome = "confirmed"
+	NameMatchRejected  NameMatchOutcome = "rejected"
+)
+
+// NameMatchDecision records what we decided when trying to match a player's name to a Discord user, for debugging
+type NameMatchDecision struct {
+	TimeUnix   int64            `json:"time"`
+	PlayerName string           `json:"playerName"`
+	Color      int              `json:"color"`
+	UserID     string           `json:"userID,omitempty"`
+	Score      float64          `json:"score"`
+	Outcome    NameMatchOutcome `json:"outcome"`
+}
+
+func NewNameMatchDecision(data PlayerData, userID string, score float64, outcome NameMatchOutcome) NameMatchDecision {
+	return NameMatchDecision{
+		TimeUnix:   time.Now().Unix(),
+		PlayerName: data.Name,
+		Color:      data.Color,
+		UserID:     userID,
+		Score:      score,
+		Outcome:    outcome,
+	}
+}
+
+func (d NameMatchDecision) String() string {
+	str := fmt.Sprintf("%s %s %.2f %s", time.Unix(d.TimeUnix, 0).UTC().Format("15:04:05"), d.PlayerName, d.Score, d.Outcome)
+	if d.UserID != "" {
+		str += " " + d.UserID
+	}
+	return str
+}
@@ -0,0 +1,60 @@
+package amongus
+
+import (
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	cases := map[string]string{
+		"Bob":          "bob",
+		"  Big Bob ":   "bigbob",
+		"Zoë":          "zoe",
+		"🔥Ember🔥":      "ember",
+		"xX_Sn1per_Xx": "xxsn1perxx",
+		"🔥":            "",
+	}
+	for in, expected := range cases {
+		if out := NormalizeName(in); out != expected {
+			t.Errorf("Expected %s to normalize to %s, got %s", in, expected, out)
+		}
+	}
+}
+
+func TestNameMatchScore(t *testing.T) {
+	if NameMatchScore("Big Bob", "bigbob") != 1 {
+		t.Error("Names differing only by case and spaces should match exactly")
+	}
+	if NameMatchScore("Zoe", "Zoë 🌸") != 1 {
+		t.Error("Names differing only by diacritics and emoji should match exactly")
+	}
+	if score := NameMatchScore("Christophe", "Christopher_Robin"); score < HighConfidenceMatch {
+		t.Errorf("Names truncated to the in-game limit should be high confidence, got %.2f", score)
+	}
+	if score := NameMatchScore("Bobb", "Bob"); score < MediumConfidenceMatch || score >= HighConfidenceMatch {
+		t.Errorf("Names a typo apart should be medium confidence, got %.2f", score)
+	}
+	if score := NameMatchScore("Christophe", "Christophr"); score >= HighConfidenceMatch {
+		t.Errorf("A typo in a name at the in-game limit shouldn't be high confidence, got %.2f", score)
+	}
+	if score := NameMatchScore("Alexandria", "Alexandrea"); score < MediumConfidenceMatch || score >= HighConfidenceMatch {
+		t.Errorf("Names at the in-game limit a letter apart should be medium confidence, got %.2f", score)
+	}
+	if score := NameMatchScore("Chris", "Christopher"); score < MediumConfidenceMatch || score >= HighConfidenceMatch {
+		t.Errorf("Short prefixes that aren't truncated should be medium confidence, got %.2f", score)
+	}
+	if score := NameMatchScore("Alice", "Bob"); score >= MediumConfidenceMatch {
+		t.Errorf("Unrelated names should be low confidence, got %.2f", score)
+	}
+	if NameMatchScore("🔥", "🔥") != 0 {
+		t.Error("Names with nothing left after normalizing should never match")
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	if d := levenshtein([]rune("kitten"), []rune("sitting")); d != 3 {
+		t.Errorf("Expected distance 3, got %d", d)
+	}
+	if d := levenshtein([]rune(""), []rune("abc")); d != 3 {
+		t.Errorf("Expected distance 3, got %d", d)
+	}
+}
@@ -0,0 +1,151 @@
+package capture
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("capture link token is invalid")
+	ErrExpiredToken = errors.New("capture link token has expired")
+	ErrRevokedToken = errors.New("capture link token has been revoked")
+	// ErrNoSession is returned for capture events that the broker didn't tag with the capture's session, which it has
+	// to for signed links to work
+	ErrNoSession      = errors.New("capture event has no session; the broker doesn't tell captures apart")
+	ErrForeignSession = errors.New("capture event is from a different capture than the one that connected")
+)
+
+// GenerateConnectCode returns 8 random uppercase hex characters. Callers are responsible for checking the code
+// against the codes of the games that are already active
+func GenerateConnectCode() (string, error) {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// NewNonce returns a random value identifying a single issued capture link, so that it can be revoked
+func NewNonce() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Signer issues and verifies the tokens embedded in capture links. A token is of the form
+// <expiry unix seconds>.<nonce>.<signature>, where the signature is an HMAC-SHA256 over the connect code, expiry and nonce
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner returns nil if the secret is empty, which means capture links are not signed
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	if secret == "" {
+		return nil
+	}
+	return &Signer{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// TTL is how long the links issued by this signer are valid for
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *Signer) Sign(connectCode, nonce string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + nonce + "." + s.signature(connectCode, exp, nonce)
+}
+
+// Verify checks the signature and expiry of a token, and returns the nonce it was issued with.
+// Whether the nonce has been revoked is up to the caller
+func (s *Signer) Verify(connectCode, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+	exp, nonce, sig := parts[0], parts[1], parts[2]
+	if !hmac.Equal([]byte(sig), []byte(s.signature(connectCode, exp, nonce))) {
+		return "", ErrInvalidToken
+	}
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if now.Unix() > expUnix {
+		return nonce, ErrExpiredToken
+	}
+	return nonce, nil
+}
+
+func (s *Signer) signature(connectCode, exp, nonce string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(fmt.Sprintf("%s:%s:%s", strings.ToUpper(connectCode), exp, nonce)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseConnection reads the payload of a connection job: "true" or "false" for whether a capture is connected. When
+// links are signed, the broker forwards the token from the link the capture connected with after the state, like
+// "true <token>"
+func ParseConnection(payload string) (connected bool, token string) {
+	state, token, _ := strings.Cut(payload, " ")
+	return state == "true", token
+}
+
+// Session is the capture that connected with a valid link, for a game with signed links. Only its events are
+// processed, so knowing the connect code isn't enough to drive a game
+type Session struct {
+	ID    string `json:"id"`
+	Nonce string `json:"nonce"`
+}
+
+// Connect returns the session after a connection event from the capture with the session ID. A capture that connects
+// takes over if verify accepts its token, and returns the link's nonce; a capture that disconnects only ends the
+// session if it's the one that connected. If there's an error, the session is unchanged
+func (s Session) Connect(id, payload string, verify func(token string) (nonce string, err error)) (next Session, connected bool, err error) {
+	if id == "" {
+		return s, false, ErrNoSession
+	}
+	connected, token := ParseConnection(payload)
+	if !connected {
+		if s.ID != "" && s.ID != id {
+			return s, false, ErrForeignSession
+		}
+		return Session{}, false, nil
+	}
+	nonce, err := verify(token)
+	if err != nil {
+		return s, false, err
+	}
+	return Session{ID: id, Nonce: nonce}, true, nil
+}
+
+// Allows is whether an event from the capture with the session ID belongs to this session
+func (s Session) Allows(id string) bool {
+	return s.ID != "" && s.ID == id
+}
+
+// SessionKey holds the session of the capture that connected for a connect code
+func SessionKey(connectCode string) string {
+	return "automuteus:capture:session:" + connectCode
+}
+
+// LinkNonceKey holds the nonce of the only capture link that is currently valid for a connect code
+func LinkNonceKey(connectCode string) string {
+	return "automuteus:capture:link:" + connectCode
+}
@@ -0,0 +1,113 @@
+package capture
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestGenerateConnectCode(t *testing.T) {
+	valid := regexp.MustCompile(`^[0-9A-F]{8}$`)
+	seen := map[string]struct{}{}
+	for i := 0; i < 100; i++ {
+		code, err := GenerateConnectCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid.MatchString(code) {
+			t.Errorf("Connect code %s should be 8 uppercase hex characters", code)
+		}
+		seen[code] = struct{}{}
+	}
+	if len(seen) < 99 {
+		t.Error("Connect codes should not repeat")
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	if NewSigner("", time.Hour) != nil {
+		t.Error("Signer with an empty secret should be nil")
+	}
+}
+
+func TestSigner_Verify(t *testing.T) {
+	signer := NewSigner("secret", time.Hour)
+	now := time.Unix(1600000000, 0)
+	token := signer.Sign("ABCD1234", "nonce", now.Add(signer.TTL()))
+
+	nonce, err := signer.Verify("ABCD1234", token, now)
+	if err != nil || nonce != "nonce" {
+		t.Error("Freshly signed token should be valid")
+	}
+	if _, err = signer.Verify("ABCD1234", token, now.Add(2*time.Hour)); !errors.Is(err, ErrExpiredToken) {
+		t.Error("Token past its expiry should be expired")
+	}
+	if _, err = signer.Verify("FFFF0000", token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Error("Token should not be valid for a different connect code")
+	}
+	if _, err = NewSigner("other", time.Hour).Verify("ABCD1234", token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Error("Token should not be valid with a different secret")
+	}
+	if _, err = signer.Verify("ABCD1234", "9999999999.nonce."+token[len(token)-10:], now); !errors.Is(err, ErrInvalidToken) {
+		t.Error("Tampered token should be invalid")
+	}
+	if _, err = signer.Verify("ABCD1234", "garbage", now); !errors.Is(err, ErrInvalidToken) {
+		t.Error("Malformed token should be invalid")
+	}
+}
+
+func TestSessionConnect(t *testing.T) {
+	verify := func(token string) (string, error) {
+		if token != "valid" {
+			return "", ErrInvalidToken
+		}
+		return "nonce", nil
+	}
+	current := Session{ID: "a", Nonce: "nonce"}
+
+	tests := []struct {
+		name      string
+		id        string
+		payload   string
+		expected  Session
+		connected bool
+		err       error
+	}{
+		{"no session", "", "true valid", current, false, ErrNoSession},
+		{"invalid token", "b", "true invalid", current, false, ErrInvalidToken},
+		{"no token", "b", "true", current, false, ErrInvalidToken},
+		{"another capture disconnecting", "b", "false", current, false, ErrForeignSession},
+		{"disconnecting", "a", "false", Session{}, false, nil},
+		{"reconnecting", "c", "true valid", Session{ID: "c", Nonce: "nonce"}, true, nil},
+	}
+	for _, tt := range tests {
+		next, connected, err := current.Connect(tt.id, tt.payload, verify)
+		if next != tt.expected || connected != tt.connected || !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %+v %v %v, got %+v %v %v", tt.name, tt.expected, tt.connected, tt.err, next, connected, err)
+		}
+	}
+
+	if !current.Allows("a") || current.Allows("b") || current.Allows("") || (Session{}).Allows("") {
+		t.Error("expected only events from the connected capture to be allowed")
+	}
+}
+
+func TestParseConnection(t *testing.T) {
+	tests := []struct {
+		payload   string
+		connected bool
+		token     string
+	}{
+		{"true", true, ""},
+		{"false", false, ""},
+		{"true 1600000000.nonce.sig", true, "1600000000.nonce.sig"},
+		{"false 1600000000.nonce.sig", false, "1600000000.nonce.sig"},
+	}
+	for _, tt := range tests {
+		connected, token := ParseConnection(tt.payload)
+		if connected != tt.connected || token != tt.token {
+			t.Errorf("%q: expected %v %q, got %v %q", tt.payload, tt.connected, tt.token, connected, token)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MaintenanceKey is set while the bot is in maintenance mode, for every shard. Its value is when maintenance is
+// expected to be over, as a unix timestamp, or 0 if that isn't known
+const MaintenanceKey = "automuteus:maintenance"
+
+// SetMaintenance turns maintenance mode on or off. eta is when maintenance is expected to be over; it can be zero
+func SetMaintenance(client *redis.Client, enabled bool, eta time.Time) error {
+	if !enabled {
+		return client.Del(context.Background(), MaintenanceKey).Err()
+	}
+	var unix int64
+	if !eta.IsZero() {
+		unix = eta.Unix()
+	}
+	return client.Set(context.Background(), MaintenanceKey, unix, 0).Err()
+}
+
+// GetMaintenance returns whether maintenance mode is on, and when it's expected to be over (zero if that isn't known)
+func GetMaintenance(client *redis.Client) (enabled bool, eta time.Time) {
+	v, err := client.Get(context.Background(), MaintenanceKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, time.Time{}
+	} else if err != nil {
+		log.Println(err)
+		return false, time.Time{}
+	}
+	unix, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || unix == 0 {
+		return true, time.Time{}
+	}
+	return true, time.Unix(unix, 0)
+}
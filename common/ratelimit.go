@@ -0,0 +1,316 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-redis/redis/v8"
+)
+
+// ComponentsBucket is the name of the bucket used for buttons and select menus, instead of a command name
+const ComponentsBucket = "components"
+
+// AnyCommandBucket is the name of the bucket that every command takes a token from, on top of its own
+const AnyCommandBucket = "any"
+
+// Bucket is a token bucket: it holds up to Capacity requests, and gets one back every Interval
+type Bucket struct {
+	Capacity int      `toml:"capacity"`
+	Interval Duration `toml:"interval"`
+}
+
+func (b Bucket) isSet() bool {
+	return b.Capacity > 0 && b.Interval.Duration > 0
+}
+
+// Duration is a time.Duration written like "1s" or "500ms" in the config file
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Limits are the buckets for each command; commands without their own bucket use Default. If Any is set, it's shared
+// by all the commands, so using lots of different commands is limited too
+type Limits struct {
+	Any      Bucket            `toml:"any"`
+	Default  Bucket            `toml:"default"`
+	Commands map[string]Bucket `toml:"commands"`
+}
+
+// TierLimits replace the base limits for guilds with a premium tier
+type TierLimits struct {
+	User  Limits `toml:"user"`
+	Guild Limits `toml:"guild"`
+}
+
+type SoftbanConfig struct {
+	// Threshold is how many violations within Window get a user softbanned
+	Threshold int      `toml:"threshold"`
+	Window    Duration `toml:"window"`
+	Duration  Duration `toml:"duration"`
+}
+
+// RateLimitConfig says how often users and guilds may use commands. Every user has a bucket per command, and every
+// guild has a bucket per command that's shared by all its members, so a single busy guild can't starve its shard
+type RateLimitConfig struct {
+	User  Limits `toml:"user"`
+	Guild Limits `toml:"guild"`
+	// Tiers are keyed by the lowercase premium tier, like "gold" or "selfhost"
+	Tiers   map[string]TierLimits `toml:"tiers"`
+	Softban SoftbanConfig         `toml:"softban"`
+}
+
+// DefaultRateLimitConfig matches what the limits were before they could be configured: one command of any kind per
+// second, one /new every 3 seconds, and a 5-minute softban for more than 3 violations in 10 minutes
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		User: Limits{
+			Any:     Bucket{Capacity: 1, Interval: Duration{time.Second}},
+			Default: Bucket{Capacity: 1, Interval: Duration{time.Second}},
+			Commands: map[string]Bucket{
+				// /new is an expensive operation
+				"new": {Capacity: 1, Interval: Duration{3 * time.Second}},
+			},
+		},
+		Guild: Limits{
+			Default: Bucket{Capacity: 30, Interval: Duration{200 * time.Millisecond}},
+		},
+		Softban: SoftbanConfig{
+			Threshold: 3,
+			Window:    Duration{10 * time.Minute},
+			Duration:  Duration{5 * time.Minute},
+		},
+	}
+}
+
+// Decode reads a TOML config over this one, and checks that the result is usable
+func (config *RateLimitConfig) Decode(r io.Reader) error {
+	md, err := toml.NewDecoder(r).Decode(config)
+	if err != nil {
+		return err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return fmt.Errorf("unknown rate limit config keys: %s", strings.Join(keys, ", "))
+	}
+	return config.Validate()
+}
+
+func (config *RateLimitConfig) Validate() error {
+	if !config.User.Default.isSet() || !config.Guild.Default.isSet() {
+		return errors.New("the default user and guild rate limits need a positive capacity and interval")
+	}
+	if config.Softban.Threshold < 1 || config.Softban.Window.Duration <= 0 || config.Softban.Duration.Duration <= 0 {
+		return errors.New("the softban needs a positive threshold, window and duration")
+	}
+	for tier := range config.Tiers {
+		if tier != strings.ToLower(tier) {
+			return fmt.Errorf("rate limit tier %q should be lowercase", tier)
+		}
+	}
+	return nil
+}
+
+// UserBucket is the bucket each user gets for the command in a guild with the premium tier
+func (config *RateLimitConfig) UserBucket(tier, command string) Bucket {
+	return config.bucket(config.User, config.Tiers[tier].User, command)
+}
+
+// GuildBucket is the bucket a guild with the premium tier gets for the command
+func (config *RateLimitConfig) GuildBucket(tier, command string) Bucket {
+	return config.bucket(config.Guild, config.Tiers[tier].Guild, command)
+}
+
+// UserAnyBucket is the bucket each user gets for all their commands in a guild with the premium tier. It isn't set if
+// all of a user's commands aren't limited together
+func (config *RateLimitConfig) UserAnyBucket(tier string) Bucket {
+	return anyBucket(config.User, config.Tiers[tier].User)
+}
+
+// GuildAnyBucket is the bucket a guild with the premium tier gets for all its commands, if there is one
+func (config *RateLimitConfig) GuildAnyBucket(tier string) Bucket {
+	return anyBucket(config.Guild, config.Tiers[tier].Guild)
+}
+
+func anyBucket(base, tier Limits) Bucket {
+	if tier.Any.isSet() {
+		return tier.Any
+	}
+	return base.Any
+}
+
+// bucket looks for the command's bucket in the tier, then in the base limits, and only then falls back to the tier's
+// default and the base default, so a tier can't accidentally loosen the limit of an expensive command like /new
+func (config *RateLimitConfig) bucket(base, tier Limits, command string) Bucket {
+	if b, ok := tier.Commands[command]; ok && b.isSet() {
+		return b
+	}
+	if b, ok := base.Commands[command]; ok && b.isSet() {
+		return b
+	}
+	if tier.Default.isSet() {
+		return tier.Default
+	}
+	return base.Default
+}
+
+// takeTokensScript refills every bucket in KEYS for the time that passed, and takes a token from each of them only if
+// they all have one, atomically so shards can't race each other and a refusal doesn't use up the other buckets. ARGV is
+// the time, then the capacity and interval of each bucket. It returns the index (from 1) of the first empty bucket and
+// how many milliseconds until it gets a token, or 0 and 0 if the tokens were taken
+var takeTokensScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local states = {}
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[2 * i])
+	local interval = tonumber(ARGV[2 * i + 1])
+	local bucket = redis.call('HMGET', key, 'tokens', 'updated')
+	local tokens = tonumber(bucket[1])
+	local updated = tonumber(bucket[2])
+	if tokens == nil or updated == nil then
+		tokens = capacity
+		updated = now
+	end
+	local refilled = math.floor((now - updated) / interval)
+	if refilled > 0 then
+		tokens = math.min(capacity, tokens + refilled)
+		updated = updated + refilled * interval
+	end
+	if tokens >= capacity then
+		updated = now
+	end
+	if tokens < 1 then
+		return {i, updated + interval - now}
+	end
+	states[i] = {capacity, interval, tokens - 1, updated}
+end
+for i, key in ipairs(KEYS) do
+	local capacity, interval, tokens, updated = unpack(states[i])
+	redis.call('HSET', key, 'tokens', tokens, 'updated', updated)
+	redis.call('PEXPIRE', key, (capacity - tokens + 1) * interval)
+end
+return {0, 0}
+`)
+
+func UserBucketKey(userID, command string) string {
+	return "automuteus:ratelimit:bucket:user:" + command + ":" + userID
+}
+
+func GuildBucketKey(guildID, command string) string {
+	return "automuteus:ratelimit:bucket:guild:" + command + ":" + guildID
+}
+
+// TakeTokens takes a token from every bucket at keys, or from none of them if one is empty. In that case, it returns the
+// index of the first empty bucket and how long until it has a token; otherwise the index is -1
+func TakeTokens(client *redis.Client, keys []string, buckets []Bucket) (int, time.Duration, error) {
+	args := []interface{}{time.Now().UnixMilli()}
+	for _, b := range buckets {
+		args = append(args, b.Capacity, b.Interval.Milliseconds())
+	}
+	res, err := takeTokensScript.Run(context.Background(), client, keys, args...).Result()
+	if err != nil {
+		return -1, 0, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return -1, 0, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+	empty, _ := vals[0].(int64)
+	wait, _ := vals[1].(int64)
+	return int(empty) - 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// RateLimitResult is why a command was refused. RetryAfter is how long until it can be used again, and Guild is
+// whether the guild's bucket was empty, rather than the user's
+type RateLimitResult struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Guild      bool
+	Softbanned bool
+}
+
+type RateLimiter struct {
+	client *redis.Client
+	config RateLimitConfig
+}
+
+func NewRateLimiter(client *redis.Client, config RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		client: client,
+		config: config,
+	}
+}
+
+type keyedBucket struct {
+	key    string
+	bucket Bucket
+	guild  bool
+}
+
+// Allow takes a token from the user's buckets for any command and for the command, and from the guild's, if there is a
+// guild. Nothing is taken unless every bucket has a token, and only emptying their own buckets counts towards a user's
+// softban. If Redis fails, the command is allowed
+func (rl *RateLimiter) Allow(userID, guildID, tier, command string) RateLimitResult {
+	buckets := []keyedBucket{
+		{key: UserBucketKey(userID, AnyCommandBucket), bucket: rl.config.UserAnyBucket(tier)},
+		{key: UserBucketKey(userID, command), bucket: rl.config.UserBucket(tier, command)},
+	}
+	if guildID != "" {
+		buckets = append(buckets,
+			keyedBucket{key: GuildBucketKey(guildID, AnyCommandBucket), bucket: rl.config.GuildAnyBucket(tier), guild: true},
+			keyedBucket{key: GuildBucketKey(guildID, command), bucket: rl.config.GuildBucket(tier, command), guild: true},
+		)
+	}
+	var set []keyedBucket
+	var keys []string
+	var limits []Bucket
+	for _, b := range buckets {
+		if b.bucket.isSet() {
+			set = append(set, b)
+			keys = append(keys, b.key)
+			limits = append(limits, b.bucket)
+		}
+	}
+	empty, retryAfter, err := TakeTokens(rl.client, keys, limits)
+	if err != nil {
+		log.Println(err)
+		return RateLimitResult{Allowed: true}
+	}
+	switch {
+	case empty < 0:
+		return RateLimitResult{Allowed: true}
+	case set[empty].guild:
+		return RateLimitResult{
+			RetryAfter: retryAfter,
+			Guild:      true,
+		}
+	default:
+		return RateLimitResult{
+			RetryAfter: retryAfter,
+			Softbanned: IncrementRateLimitExceed(rl.client, userID, rl.config.Softban),
+		}
+	}
+}
+
+// SoftbanDuration is how long users that get softbanned are ignored for
+func (rl *RateLimiter) SoftbanDuration() time.Duration {
+	return rl.config.Softban.Duration.Duration
+}
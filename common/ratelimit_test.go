@@ -0,0 +1,96 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultRateLimitConfig(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if b := config.UserBucket("free", "link"); b.Capacity != 1 || b.Interval.Duration != time.Second {
+		t.Errorf("expected 1 command per second, got %+v", b)
+	}
+	if b := config.UserBucket("free", "new"); b.Interval.Duration != 3*time.Second {
+		t.Errorf("expected /new every 3 seconds, got %+v", b)
+	}
+	if b := config.UserAnyBucket("free"); b.Capacity != 1 || b.Interval.Duration != time.Second {
+		t.Errorf("expected 1 command of any kind per second, got %+v", b)
+	}
+	if b := config.GuildAnyBucket("free"); b.isSet() {
+		t.Errorf("expected no limit on all of a guild's commands, got %+v", b)
+	}
+}
+
+func TestRateLimitConfigDecode(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	err := config.Decode(strings.NewReader(`
+[user.commands.stats]
+capacity = 2
+interval = "5s"
+
+[guild.default]
+capacity = 100
+interval = "100ms"
+
+[tiers.gold.user.default]
+capacity = 3
+interval = "1s"
+
+[tiers.gold.user.any]
+capacity = 5
+interval = "1s"
+
+[tiers.gold.guild.commands.new]
+capacity = 10
+interval = "1s"
+
+[softban]
+threshold = 5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		bucket   Bucket
+		capacity int
+		interval time.Duration
+	}{
+		{"command override", config.UserBucket("free", "stats"), 2, 5 * time.Second},
+		{"default kept", config.UserBucket("free", "link"), 1, time.Second},
+		{"tier default", config.UserBucket("gold", "link"), 3, time.Second},
+		{"base command before tier default", config.UserBucket("gold", "new"), 1, 3 * time.Second},
+		{"guild default", config.GuildBucket("free", "new"), 100, 100 * time.Millisecond},
+		{"tier command", config.GuildBucket("gold", "new"), 10, time.Second},
+		{"unknown tier", config.GuildBucket("silver", "new"), 100, 100 * time.Millisecond},
+		{"any command", config.UserAnyBucket("free"), 1, time.Second},
+		{"tier any command", config.UserAnyBucket("gold"), 5, time.Second},
+	}
+	for _, tt := range tests {
+		if tt.bucket.Capacity != tt.capacity || tt.bucket.Interval.Duration != tt.interval {
+			t.Errorf("%s: expected %d per %s, got %+v", tt.name, tt.capacity, tt.interval, tt.bucket)
+		}
+	}
+	if config.Softban.Threshold != 5 || config.Softban.Duration.Duration != 5*time.Minute {
+		t.Errorf("expected only the softban threshold to change, got %+v", config.Softban)
+	}
+}
+
+func TestRateLimitConfigDecodeInvalid(t *testing.T) {
+	for _, input := range []string{
+		"[user.default]\ncapacity = 0",
+		"[guild.default]\ninterval = \"soon\"",
+		"[softban]\nthreshhold = 3",
+		"[tiers.Gold.user.default]\ncapacity = 3\ninterval = \"1s\"",
+	} {
+		config := DefaultRateLimitConfig()
+		if err := config.Decode(strings.NewReader(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
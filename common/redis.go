@@ -5,30 +5,10 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"log"
+	"strings"
 	"time"
 )
 
-const GlobalUserRateLimitDuration = 1 * time.Second
-
-const NewGameRateLimitDuration = 3000 * time.Millisecond
-
-// when a user exceeds the threshold, they're ignored for this long
-const SoftbanDuration = 5 * time.Minute
-
-// how many violations before a softban
-const SoftbanThreshold = 3
-
-// how far back the bot should look for violations. Softban is invoked by violations>threshold in this amt of time
-const SoftbanExpiration = 10 * time.Minute
-
-func UserRateLimitGeneralKey(userID string) string {
-	return "automuteus:ratelimit:user:" + userID
-}
-
-func UserRateLimitSpecificKey(userID, cmdType string) string {
-	return "automuteus:ratelimit:user:" + cmdType + ":" + userID
-}
-
 func UserSoftbanKey(userID string) string {
 	return "automuteus:ratelimit:softban:user:" + userID
 }
@@ -37,21 +17,8 @@ func UserSoftbanCountKey(userID string) string {
 	return "automuteus:ratelimit:softban:count:user:" + userID
 }
 
-func MarkUserRateLimit(client *redis.Client, userID, cmdType string, ttl time.Duration) {
-	err := client.Set(context.Background(), UserRateLimitGeneralKey(userID), "", GlobalUserRateLimitDuration).Err()
-	if err != nil {
-		log.Println(err)
-	}
-
-	if cmdType != "" && ttl > 0 {
-		err = client.Set(context.Background(), UserRateLimitSpecificKey(userID, cmdType), "", ttl).Err()
-		if err != nil {
-			log.Println(err)
-		}
-	}
-}
-
-func IncrementRateLimitExceed(client *redis.Client, userID string) bool {
+// IncrementRateLimitExceed records a violation, and softbans the user if they had too many recently
+func IncrementRateLimitExceed(client *redis.Client, userID string, softban SoftbanConfig) bool {
 	t := time.Now().Unix()
 	_, err := client.ZAdd(context.Background(), UserSoftbanCountKey(userID), &redis.Z{
 		Score:  float64(t),
@@ -61,7 +28,7 @@ func IncrementRateLimitExceed(client *redis.Client, userID string) bool {
 		log.Println(err)
 	}
 
-	beforeStr := fmt.Sprintf("%d", time.Now().Add(-SoftbanExpiration).Unix())
+	beforeStr := fmt.Sprintf("%d", time.Now().Add(-softban.Window.Duration).Unix())
 
 	count, err := client.ZCount(context.Background(), UserSoftbanCountKey(userID),
 		beforeStr,
@@ -70,8 +37,8 @@ func IncrementRateLimitExceed(client *redis.Client, userID string) bool {
 	if err != nil {
 		log.Println(err)
 	}
-	if count > SoftbanThreshold {
-		softbanUser(client, userID)
+	if count > int64(softban.Threshold) {
+		softbanUser(client, userID, softban.Duration.Duration)
 		return true
 	}
 
@@ -80,8 +47,8 @@ func IncrementRateLimitExceed(client *redis.Client, userID string) bool {
 	return false
 }
 
-func softbanUser(client *redis.Client, userID string) {
-	err := client.Set(context.Background(), UserSoftbanKey(userID), "", SoftbanDuration).Err()
+func softbanUser(client *redis.Client, userID string, duration time.Duration) {
+	err := client.Set(context.Background(), UserSoftbanKey(userID), "", duration).Err()
 	if err != nil {
 		log.Println(err)
 	}
@@ -96,20 +63,28 @@ func IsUserBanned(client *redis.Client, userID string) bool {
 	return v == 1 // =1 means the user is present, and thus rate-limited
 }
 
-func IsUserRateLimitedGeneral(client *redis.Client, userID string) bool {
-	v, err := client.Exists(context.Background(), UserRateLimitGeneralKey(userID)).Result()
-	if err != nil {
-		log.Println(err)
-		return false
+// SoftbannedUsers returns the users that are softbanned right now, and how much longer they're banned for
+func SoftbannedUsers(client *redis.Client) (map[string]time.Duration, error) {
+	prefix := UserSoftbanKey("")
+	banned := make(map[string]time.Duration)
+	iter := client.Scan(context.Background(), 0, prefix+"*", 0).Iterator()
+	for iter.Next(context.Background()) {
+		ttl, err := client.TTL(context.Background(), iter.Val()).Result()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		// the ban expired between the scan and now
+		if ttl < 0 {
+			continue
+		}
+		banned[strings.TrimPrefix(iter.Val(), prefix)] = ttl
 	}
-	return v == 1 // =1 means the user is present, and thus rate-limited
+	return banned, iter.Err()
 }
 
-func IsUserRateLimitedSpecific(client *redis.Client, userID string, cmdType string) bool {
-	v, err := client.Exists(context.Background(), UserRateLimitSpecificKey(userID, cmdType)).Result()
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-	return v == 1 // =1 means the user is present, and thus rate-limited
+// LiftSoftban unbans the user, and forgets their previous violations so they aren't banned again right away
+func LiftSoftban(client *redis.Client, userID string) (bool, error) {
+	wasBanned := IsUserBanned(client, userID)
+	return wasBanned, client.Del(context.Background(), UserSoftbanKey(userID), UserSoftbanCountKey(userID)).Err()
 }
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/logging"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultHost                  = "http://localhost:8123"
+	DefaultLogPath               = "./"
+	DefaultListeningTo           = "/help"
+	DefaultCaptureLinkTTLMinutes = 60
+	DefaultLogMaxSizeMB          = 100
+	DefaultLogMaxBackups         = 5
+)
+
+const redacted = "REDACTED"
+
+// Config is everything AutoMuteUs can be configured with. Each field can be set in the config file, and overridden by
+// the environment variables in its env tag. The first variable is the preferred name; the rest are older names that
+// still work. Fields tagged secret are redacted when the config is printed
+type Config struct {
+	// Official is only set for the official AutoMuteUs bot
+	Official bool `toml:"official" yaml:"official" env:"AUTOMUTEUS_OFFICIAL"`
+	// Host is the URL that capture links point to
+	Host       string `toml:"host" yaml:"host" env:"AUTOMUTEUS_HOST,HOST"`
+	BaseMapURL string `toml:"base_map_url" yaml:"base_map_url" env:"AUTOMUTEUS_BASE_MAP_URL,BASE_MAP_URL"`
+	// ListeningTo is shown as the bot's activity
+	ListeningTo string `toml:"listening_to" yaml:"listening_to" env:"AUTOMUTEUS_LISTENING"`
+	// NodeID labels the metrics of this instance
+	NodeID string `toml:"node_id" yaml:"node_id" env:"AUTOMUTEUS_NODE_ID,SCW_NODE_ID"`
+	// RateLimitFile is a TOML file that's read over RateLimits, for the deployments that still keep them separately
+	RateLimitFile string                       `toml:"rate_limit_file" yaml:"rate_limit_file" env:"AUTOMUTEUS_RATE_LIMIT_CONFIG,RATE_LIMIT_CONFIG"`
+	RateLimits    redis_common.RateLimitConfig `toml:"rate_limits" yaml:"rate_limits"`
+
+	Discord  DiscordConfig  `toml:"discord" yaml:"discord"`
+	Redis    RedisConfig    `toml:"redis" yaml:"redis"`
+	Postgres PostgresConfig `toml:"postgres" yaml:"postgres"`
+	Galactus GalactusConfig `toml:"galactus" yaml:"galactus"`
+	Log      LogConfig      `toml:"log" yaml:"log"`
+	Locale   LocaleConfig   `toml:"locale" yaml:"locale"`
+	Capture  CaptureConfig  `toml:"capture" yaml:"capture"`
+	TopGG    TopGGConfig    `toml:"top_gg" yaml:"top_gg"`
+}
+
+type DiscordConfig struct {
+	Token     string `toml:"token" yaml:"token" env:"AUTOMUTEUS_DISCORD_TOKEN,DISCORD_BOT_TOKEN" secret:"true"`
+	NumShards int    `toml:"num_shards" yaml:"num_shards" env:"AUTOMUTEUS_NUM_SHARDS,NUM_SHARDS"`
+	ShardID   int    `toml:"shard_id" yaml:"shard_id" env:"AUTOMUTEUS_SHARD_ID,SHARD_ID"`
+	// SlashCommandGuildIDs are the guilds the commands are registered in; they're registered globally if it's empty
+	SlashCommandGuildIDs []string `toml:"slash_command_guild_ids" yaml:"slash_command_guild_ids" env:"AUTOMUTEUS_SLASH_COMMAND_GUILD_IDS,SLASH_COMMAND_GUILD_IDS"`
+	// AdminGuildID is the only guild the owner command is registered in
+	AdminGuildID string   `toml:"admin_guild_id" yaml:"admin_guild_id" env:"AUTOMUTEUS_ADMIN_GUILD_ID,ADMIN_GUILD_ID"`
+	EmojiGuildID string   `toml:"emoji_guild_id" yaml:"emoji_guild_id" env:"AUTOMUTEUS_EMOJI_GUILD_ID,EMOJI_GUILD_ID"`
+	OwnerUserIDs []string `toml:"owner_user_ids" yaml:"owner_user_ids" env:"AUTOMUTEUS_OWNER_USER_IDS,OWNER_USER_IDS"`
+}
+
+type RedisConfig struct {
+	Addr     string `toml:"addr" yaml:"addr" env:"AUTOMUTEUS_REDIS_ADDR,REDIS_ADDR"`
+	Password string `toml:"password" yaml:"password" env:"AUTOMUTEUS_REDIS_PASS,REDIS_PASS" secret:"true"`
+}
+
+type PostgresConfig struct {
+	Addr     string `toml:"addr" yaml:"addr" env:"AUTOMUTEUS_POSTGRES_ADDR,POSTGRES_ADDR"`
+	User     string `toml:"user" yaml:"user" env:"AUTOMUTEUS_POSTGRES_USER,POSTGRES_USER"`
+	Password string `toml:"password" yaml:"password" env:"AUTOMUTEUS_POSTGRES_PASS,POSTGRES_PASS" secret:"true"`
+}
+
+type GalactusConfig struct {
+	Addr string `toml:"addr" yaml:"addr" env:"AUTOMUTEUS_GALACTUS_ADDR,GALACTUS_ADDR"`
+}
+
+type LogConfig struct {
+	Path        string `toml:"path" yaml:"path" env:"AUTOMUTEUS_LOG_PATH,LOG_PATH"`
+	DisableFile bool   `toml:"disable_file" yaml:"disable_file" env:"AUTOMUTEUS_DISABLE_LOG_FILE,DISABLE_LOG_FILE"`
+	// Level is debug, info, warn or error
+	Level string `toml:"level" yaml:"level" env:"AUTOMUTEUS_LOG_LEVEL,LOG_LEVEL"`
+	JSON  bool   `toml:"json" yaml:"json" env:"AUTOMUTEUS_LOG_JSON"`
+	// the log file is rotated when it's bigger than MaxSizeMB, and MaxBackups old files are kept
+	MaxSizeMB  int `toml:"max_size_mb" yaml:"max_size_mb" env:"AUTOMUTEUS_LOG_MAX_SIZE_MB"`
+	MaxBackups int `toml:"max_backups" yaml:"max_backups" env:"AUTOMUTEUS_LOG_MAX_BACKUPS"`
+	// DebugGuildIDs are logged at the debug level, whatever Level is
+	DebugGuildIDs []string `toml:"debug_guild_ids" yaml:"debug_guild_ids" env:"AUTOMUTEUS_LOG_DEBUG_GUILD_IDS"`
+}
+
+type LocaleConfig struct {
+	Path string `toml:"path" yaml:"path" env:"AUTOMUTEUS_LOCALE_PATH,LOCALE_PATH"`
+	Lang string `toml:"lang" yaml:"lang" env:"AUTOMUTEUS_BOT_LANG,BOT_LANG"`
+}
+
+type CaptureConfig struct {
+	// LinkSecret signs capture links; they aren't signed if it's empty. When it's set, the broker has to forward the
+	// token from the link the capture connected with, like "true <token>", and tag every event with the "session" of
+	// the capture connection it came from. Events from brokers that don't are refused
+	LinkSecret     string `toml:"link_secret" yaml:"link_secret" env:"AUTOMUTEUS_CAPTURE_LINK_SECRET,CAPTURE_LINK_SECRET" secret:"true"`
+	LinkTTLMinutes int    `toml:"link_ttl_minutes" yaml:"link_ttl_minutes" env:"AUTOMUTEUS_CAPTURE_LINK_TTL_MINUTES,CAPTURE_LINK_TTL_MINUTES"`
+}
+
+type TopGGConfig struct {
+	Token string `toml:"token" yaml:"token" env:"AUTOMUTEUS_TOP_GG_TOKEN,TOP_GG_TOKEN" secret:"true"`
+}
+
+// Errors are all the problems found with a config, so they can be fixed at once
+type Errors []string
+
+func (errs Errors) Error() string {
+	return "invalid config:\n  " + strings.Join(errs, "\n  ")
+}
+
+func Default() *Config {
+	return &Config{
+		Host:        DefaultHost,
+		ListeningTo: DefaultListeningTo,
+		Discord: DiscordConfig{
+			NumShards: 1,
+		},
+		Log: LogConfig{
+			Path:       DefaultLogPath,
+			Level:      "info",
+			MaxSizeMB:  DefaultLogMaxSizeMB,
+			MaxBackups: DefaultLogMaxBackups,
+		},
+		Capture: CaptureConfig{
+			LinkTTLMinutes: DefaultCaptureLinkTTLMinutes,
+		},
+		RateLimits: redis_common.DefaultRateLimitConfig(),
+	}
+}
+
+// Load reads the config file at path over the defaults, if path isn't empty, and then the environment variables over
+// that. Variables that aren't set in the environment are looked up in the .env file in the working directory, if there
+// is one. Whether the config file is TOML or YAML depends on its extension
+func Load(path string) (*Config, error) {
+	config := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = config.decode(data, filepath.Ext(path))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %s: %w", path, err)
+		}
+	}
+	dotEnv, err := loadDotEnv(".")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", DotEnvFile, err)
+	}
+	errs := applyEnv(reflect.ValueOf(config).Elem(), func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			return value, true
+		}
+		value, ok := dotEnv[name]
+		return value, ok
+	})
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if config.RateLimitFile != "" {
+		err = config.loadRateLimitFile()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %s: %w", config.RateLimitFile, err)
+		}
+	}
+	return config, nil
+}
+
+func (config *Config) loadRateLimitFile() error {
+	f, err := os.Open(config.RateLimitFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return config.RateLimits.Decode(f)
+}
+
+func (config *Config) decode(data []byte, ext string) error {
+	switch strings.ToLower(ext) {
+	case ".toml":
+		md, err := toml.Decode(string(data), config)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+		}
+		return nil
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		return decoder.Decode(config)
+	default:
+		return fmt.Errorf("unknown config file extension %q; use .toml, .yaml or .yml", ext)
+	}
+}
+
+// applyEnv sets each field from the first of its environment variables that isn't empty
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) Errors {
+	var errs Errors
+	for i := 0; i < v.NumField(); i++ {
+		field, structField := v.Field(i), v.Type().Field(i)
+		if field.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(field, lookup)...)
+			continue
+		}
+		for _, name := range strings.Split(structField.Tag.Get("env"), ",") {
+			value, _ := lookup(name)
+			if name == "" || value == "" {
+				continue
+			}
+			if err := setField(field, value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			}
+			break
+		}
+	}
+	return errs
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(strings.TrimSpace(value))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			// these used to be enabled by setting them to anything at all
+			b = true
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate checks everything the bot needs to run, and reports every problem it finds
+func (config *Config) Validate() error {
+	var errs Errors
+	required := func(value, name string) {
+		if value == "" {
+			errs = append(errs, name+" is required")
+		}
+	}
+	snowflake := func(value, name string) {
+		if _, err := strconv.ParseUint(value, 10, 64); value != "" && err != nil {
+			errs = append(errs, fmt.Sprintf("%s %q isn't a Discord ID", name, value))
+		}
+	}
+
+	required(config.Discord.Token, "discord.token")
+	required(config.Redis.Addr, "redis.addr")
+	required(config.Postgres.Addr, "postgres.addr")
+	required(config.Postgres.User, "postgres.user")
+	required(config.Postgres.Password, "postgres.password")
+	required(config.Galactus.Addr, "galactus.addr")
+
+	if config.Discord.NumShards < 1 {
+		errs = append(errs, "discord.num_shards must be at least 1")
+	}
+	if config.Discord.ShardID < 0 || config.Discord.ShardID >= config.Discord.NumShards {
+		errs = append(errs, "discord.shard_id must be at least 0, and lower than discord.num_shards")
+	}
+	for _, id := range config.Discord.SlashCommandGuildIDs {
+		snowflake(id, "discord.slash_command_guild_ids")
+	}
+	snowflake(config.Discord.AdminGuildID, "discord.admin_guild_id")
+	snowflake(config.Discord.EmojiGuildID, "discord.emoji_guild_id")
+	for _, id := range config.Discord.OwnerUserIDs {
+		snowflake(id, "discord.owner_user_ids")
+	}
+
+	if u, err := url.Parse(config.Host); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Sprintf("host %q must be an http or https URL", config.Host))
+	}
+	if _, err := logging.ParseLevel(config.Log.Level); err != nil {
+		errs = append(errs, "log.level: "+err.Error())
+	}
+	if config.Log.MaxSizeMB < 1 {
+		errs = append(errs, "log.max_size_mb must be at least 1")
+	}
+	if config.Log.MaxBackups < 0 {
+		errs = append(errs, "log.max_backups can't be negative")
+	}
+	for _, id := range config.Log.DebugGuildIDs {
+		snowflake(id, "log.debug_guild_ids")
+	}
+	if config.Capture.LinkTTLMinutes < 1 {
+		errs = append(errs, "capture.link_ttl_minutes must be at least 1")
+	}
+	if err := config.RateLimits.Validate(); err != nil {
+		errs = append(errs, "rate_limits: "+err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Redacted is a copy of the config with the secrets that are set replaced, so it can be printed
+func (config *Config) Redacted() *Config {
+	c := *config
+	c.Discord.SlashCommandGuildIDs = append([]string(nil), config.Discord.SlashCommandGuildIDs...)
+	c.Discord.OwnerUserIDs = append([]string(nil), config.Discord.OwnerUserIDs...)
+	c.Log.DebugGuildIDs = append([]string(nil), config.Log.DebugGuildIDs...)
+	redact(reflect.ValueOf(&c).Elem())
+	return &c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			redact(field)
+		} else if v.Type().Field(i).Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+}
+
+// TOML is the config with its secrets redacted, in the config file format
+func (config *Config) TOML() (string, error) {
+	buf := bytes.Buffer{}
+	err := toml.NewEncoder(&buf).Encode(config.Redacted())
+	return buf.String(), err
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	config := Default()
+	errs := applyEnv(reflect.ValueOf(config).Elem(), lookup(map[string]string{
+		"DISCORD_BOT_TOKEN":        "old",
+		"AUTOMUTEUS_DISCORD_TOKEN": "new",
+		"REDIS_ADDR":               "redis:6379",
+		"NUM_SHARDS":               "4",
+		"SLASH_COMMAND_GUILD_IDS":  "1, 2,",
+		"DISABLE_LOG_FILE":         "yes",
+		"AUTOMUTEUS_OFFICIAL":      "false",
+		"HOST":                     "",
+	}))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if config.Discord.Token != "new" {
+		t.Errorf("expected the new name to take precedence over the old one, got %q", config.Discord.Token)
+	}
+	if config.Redis.Addr != "redis:6379" || config.Discord.NumShards != 4 {
+		t.Errorf("expected the old names to still work, got %+v", config)
+	}
+	if !reflect.DeepEqual(config.Discord.SlashCommandGuildIDs, []string{"1", "2"}) {
+		t.Errorf("expected 2 guild IDs, got %v", config.Discord.SlashCommandGuildIDs)
+	}
+	if !config.Log.DisableFile || config.Official {
+		t.Errorf("expected any value but false to enable a bool, got %+v", config)
+	}
+	if config.Host != DefaultHost {
+		t.Errorf("expected an empty variable to be ignored, got %q", config.Host)
+	}
+
+	errs = applyEnv(reflect.ValueOf(Default()).Elem(), lookup(map[string]string{
+		"NUM_SHARDS": "four",
+		"SHARD_ID":   "one",
+	}))
+	if len(errs) != 2 {
+		t.Errorf("expected both numbers to be reported, got %v", errs)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.toml": "host = \"https://example.com\"\n[discord]\nnum_shards = 2\nowner_user_ids = [\"1\"]\n",
+		"config.yaml": "host: https://example.com\ndiscord:\n  num_shards: 2\n  owner_user_ids: [\"1\"]\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := Load(p)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if config.Host != "https://example.com" || config.Discord.NumShards != 2 || len(config.Discord.OwnerUserIDs) != 1 {
+			t.Errorf("%s: unexpected config %+v", name, config)
+		}
+		if config.Log.Path != DefaultLogPath {
+			t.Errorf("%s: expected the defaults to be kept, got %q", name, config.Log.Path)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"typo.toml":   "[discord]\nnum_shard = 2\n",
+		"typo.yml":    "discord:\n  num_shard: 2\n",
+		"config.json": "{}",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadRateLimits(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.toml":      "[rate_limits.user.commands.new]\ncapacity = 2\ninterval = \"5s\"\n",
+		"config.yaml":      "rate_limits:\n  user:\n    commands:\n      new: {capacity: 2, interval: 5s}\n",
+		"rate_limits.toml": "[user.commands.new]\ncapacity = 2\ninterval = \"5s\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"config.toml", "config.yaml", "rate_limits.toml"} {
+		path := filepath.Join(dir, name)
+		if name == "rate_limits.toml" {
+			t.Setenv("AUTOMUTEUS_RATE_LIMIT_CONFIG", path)
+			path = ""
+		}
+		config, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		bucket := config.RateLimits.UserBucket("free", "new")
+		if bucket.Capacity != 2 || bucket.Interval.Duration != 5*time.Second {
+			t.Errorf("%s: expected /new's bucket to be read, got %+v", name, bucket)
+		}
+		if config.RateLimits.Softban.Threshold == 0 {
+			t.Errorf("%s: expected the default rate limits to be kept", name)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.toml")
+	if err := os.WriteFile(bad, []byte("[user.default]\ncapacity = 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTOMUTEUS_RATE_LIMIT_CONFIG", bad)
+	if _, err := Load(""); err == nil {
+		t.Error("expected an invalid rate limit file to fail loading")
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	env, err := loadDotEnv(dir)
+	if err != nil || env != nil {
+		t.Fatalf("expected a missing .env to be ignored, got %v, %v", env, err)
+	}
+
+	dotEnv := "# comment\nDISCORD_BOT_TOKEN=\"token\"\nREDIS_PASS=a=b\nnot a variable\n"
+	if err := os.WriteFile(filepath.Join(dir, DotEnvFile), []byte(dotEnv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env, err = loadDotEnv(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"DISCORD_BOT_TOKEN": "token", "REDIS_PASS": "a=b"}) {
+		t.Errorf("unexpected variables %v", env)
+	}
+
+	diffEnvs := `{"automuteus": [{"AUTOMUTEUS_DISCORD_TOKEN": "DISCORD_BOT_TOKEN"}, {"REDIS_PASS": "REDIS_PASS"}]}`
+	if err := os.WriteFile(filepath.Join(dir, DiffEnvsFile), []byte(diffEnvs), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env, err = loadDotEnv(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"AUTOMUTEUS_DISCORD_TOKEN": "token", "REDIS_PASS": "a=b"}) {
+		t.Errorf("expected the variables to be renamed, got %v", env)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Discord.ShardID = 1
+	config.Discord.OwnerUserIDs = []string{"me"}
+	config.Host = "localhost"
+	config.Capture.LinkTTLMinutes = 0
+	config.Log.Level = "verbose"
+	config.RateLimits.Softban.Threshold = 0
+
+	err := config.Validate()
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected config errors, got %v", err)
+	}
+	// the 6 required fields, the shard ID, the owner ID, the host, the log level, the link TTL and the rate limits
+	if len(errs) != 12 {
+		t.Errorf("expected every problem to be reported, got %d:\n%v", len(errs), err)
+	}
+
+	config = Default()
+	config.Discord.Token = "token"
+	config.Redis.Addr = "redis:6379"
+	config.Postgres = PostgresConfig{Addr: "postgres:5432", User: "postgres", Password: "password"}
+	config.Galactus.Addr = "http://galactus:5858"
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := Default()
+	config.Discord.Token = "token"
+	config.Postgres.Password = "password"
+	config.Postgres.User = "postgres"
+
+	out, err := config.TOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "token\"") || strings.Contains(out, "\"password\"") {
+		t.Errorf("expected the secrets to be redacted:\n%s", out)
+	}
+	if !strings.Contains(out, "\"postgres\"") || !strings.Contains(out, redacted) {
+		t.Errorf("expected everything else to be printed:\n%s", out)
+	}
+	if config.Discord.Token != "token" {
+		t.Error("expected the original config to be unchanged")
+	}
+	if !strings.Contains(out, "[rate_limits.softban]") {
+		t.Errorf("expected the rate limits to be printed:\n%s", out)
+	}
+	if strings.Count(out, redacted) != 2 {
+		t.Errorf("expected secrets that aren't set to stay empty:\n%s", out)
+	}
+}
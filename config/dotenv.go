@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DotEnvFile = ".env"
+	// DiffEnvsFile renames the variables in the .env file, for launchers (like the Windows release's) that use their
+	// own names
+	DiffEnvsFile = "diffenvs.json"
+)
+
+// loadDotEnv reads the KEY=VALUE lines of the .env file in dir, if there is one. If there's a diffenvs.json next to it,
+// the variables are renamed with its "automuteus" entries, which map our names to the names in the .env file
+func loadDotEnv(dir string) (map[string]string, error) {
+	file, err := os.Open(filepath.Join(dir, DotEnvFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			continue
+		}
+		env[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, DiffEnvsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return env, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var diffEnvs map[string][]map[string]string
+	if err := json.Unmarshal(data, &diffEnvs); err != nil {
+		return nil, err
+	}
+	renamed := map[string]string{}
+	for _, names := range diffEnvs["automuteus"] {
+		for ours, theirs := range names {
+			renamed[ours] = env[theirs]
+		}
+	}
+	return renamed, nil
+}
@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func (bot *Bot) handleAutocomplete(i *discordgo.InteractionCreate, gsr GameStateRequest, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	focused := command.GetFocusedOption(i.ApplicationCommandData().Options)
+	if focused == nil {
+		return command.AutocompleteResponse(nil)
+	}
+	typed := strings.ToLower(strings.TrimSpace(focused.StringValue()))
+
+	switch i.ApplicationCommandData().Name {
+	case command.Link.Name:
+		dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+		if dgs == nil {
+			return command.AutocompleteResponse(nil)
+		}
+		return command.AutocompleteResponse(dgs.unlinkedColorChoices(typed))
+
+	case command.Stats.Name:
+		games, err := storage.GetRecentGames(bot.PostgresInterface, i.GuildID, typed, command.MaxAutocompleteChoices)
+		if err != nil {
+			log.Println(err)
+			return command.AutocompleteResponse(nil)
+		}
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, len(games))
+		for idx, g := range games {
+			choices[idx] = command.MatchIDChoice(matchIDCode(g.ConnectCode, g.GameID), int64(g.StartTime), game.GameResult(g.WinType), sett)
+		}
+		return command.AutocompleteResponse(choices)
+
+	case command.Settings.Name:
+		// the only autocompleted setting is the command to change the permissions of
+		return command.AutocompleteResponse(command.PermissionKeyChoices(typed))
+	}
+	return command.AutocompleteResponse(nil)
+}
+
+// unlinkedColorChoices are the colors of the players nobody is linked to yet, whose color or name contains typed
+func (dgs *GameState) unlinkedColorChoices(typed string) []*discordgo.ApplicationCommandOptionChoice {
+	colors := make([]int, 0, len(dgs.GameData.PlayerData))
+	for color, player := range dgs.GameData.PlayerData {
+		linked := false
+		for _, userData := range dgs.UserData {
+			if userData.IsLinkedTo(player) {
+				linked = true
+				break
+			}
+		}
+		colorStr := game.GetColorStringForInt(color)
+		if !linked && (strings.Contains(colorStr, typed) || strings.Contains(strings.ToLower(player.Name), typed)) {
+			colors = append(colors, color)
+		}
+	}
+	sort.Ints(colors)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(colors))
+	for idx, color := range colors {
+		choices[idx] = command.PlayerColorChoice(game.GetColorStringForInt(color), dgs.GameData.PlayerData[color].Name)
+	}
+	return choices
+}
@@ -5,7 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/capture"
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/config"
 	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/job"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/automuteus/metrics"
 	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
@@ -17,8 +22,6 @@ import (
 	"github.com/automuteus/utils/pkg/token"
 	"github.com/bwmarrin/discordgo"
 	"github.com/top-gg/go-dbl"
-	"log"
-	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -45,6 +48,14 @@ type Bot struct {
 
 	RedisInterface *RedisInterface
 
+	// JobSource delivers the capture events for each game; Redis unless overridden
+	JobSource job.Source
+
+	// captureSigner signs capture links; nil if CAPTURE_LINK_SECRET isn't set
+	captureSigner *capture.Signer
+
+	profiles profileCache
+
 	StorageInterface *storage.StorageInterface
 
 	PostgresInterface *storageutils.PsqlInterface
@@ -52,26 +63,37 @@ type Bot struct {
 	logPath string
 
 	captureTimeout int
+
+	// ownerIDs can use the owner command
+	ownerIDs []string
+
+	rateLimiter *redis_common.RateLimiter
+
+	// debugGuildIDs are always logged at the debug level, on top of the ones the owners turned on
+	debugGuildIDs []string
+
+	// listeningTo is shown as the bot's activity, unless it's in maintenance mode
+	listeningTo string
 }
 
 // MakeAndStartBot does what it sounds like
-// TODO collapse these fields into proper structs?
-func MakeAndStartBot(version, commit, botToken, topGGToken, url, emojiGuildID string, numShards, shardID int, redisInterface *RedisInterface, storageInterface *storage.StorageInterface, psql *storageutils.PsqlInterface, gc *GalactusClient, logPath string) *Bot {
-	dg, err := discordgo.New("Bot " + botToken)
+func MakeAndStartBot(version, commit string, cfg *config.Config, redisInterface *RedisInterface, storageInterface *storage.StorageInterface, psql *storageutils.PsqlInterface, gc *GalactusClient) *Bot {
+	dg, err := discordgo.New("Bot " + cfg.Discord.Token)
 	if err != nil {
-		log.Println("error creating Discord session,", err)
+		logging.Default().Error("Couldn't create the Discord session", "err", err)
 		return nil
 	}
 
-	if numShards > 1 {
-		log.Printf("Identifying to the Discord API with %d total shards, and shard ID=%d\n", numShards, shardID)
-		dg.ShardCount = numShards
-		dg.ShardID = shardID
+	if cfg.Discord.NumShards > 1 {
+		logging.Default().Info("Identifying to the Discord API", "shards", cfg.Discord.NumShards)
+		dg.ShardCount = cfg.Discord.NumShards
+		dg.ShardID = cfg.Discord.ShardID
 	}
+	baseMapURL = cfg.BaseMapURL
 
 	bot := Bot{
-		official:     os.Getenv("AUTOMUTEUS_OFFICIAL") != "",
-		url:          url,
+		official:     cfg.Official,
+		url:          cfg.Host,
 		ConnsToGames: make(map[string]string),
 		StatusEmojis: emptyStatusEmojis(),
 
@@ -80,79 +102,72 @@ func MakeAndStartBot(version, commit, botToken, topGGToken, url, emojiGuildID st
 		PrimarySession:    dg,
 		GalactusClient:    gc,
 		RedisInterface:    redisInterface,
+		JobSource:         job.NewRedisSource(redisInterface.client),
+		captureSigner:     capture.NewSigner(cfg.Capture.LinkSecret, time.Duration(cfg.Capture.LinkTTLMinutes)*time.Minute),
 		StorageInterface:  storageInterface,
 		PostgresInterface: psql,
-		logPath:           logPath,
+		logPath:           cfg.Log.Path,
 		captureTimeout:    GameTimeoutSeconds,
+		ownerIDs:          cfg.Discord.OwnerUserIDs,
+		rateLimiter:       redis_common.NewRateLimiter(redisInterface.client, cfg.RateLimits),
+		listeningTo:       cfg.ListeningTo,
+		debugGuildIDs:     cfg.Log.DebugGuildIDs,
 	}
 	dg.LogLevel = discordgo.LogInformational
 
 	dg.AddHandler(bot.handleVoiceStateChange)
-	dg.AddHandler(bot.newGuild(emojiGuildID))
+	dg.AddHandler(bot.newGuild(cfg.Discord.EmojiGuildID))
 	dg.AddHandler(bot.leaveGuild)
 	dg.AddHandler(bot.rateLimitEventCallback)
 	// Slash commands
 	dg.AddHandler(bot.handleInteractionCreate)
 
 	dg.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
-		log.Println("Bot is now online according to discord Ready handler")
+		logging.Default().Info("Bot is now online according to discord Ready handler")
 	})
 
 	dg.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuildVoiceStates | discordgo.IntentsGuilds | discordgo.IntentsGuildMessages)
 
-	token.WaitForToken(bot.RedisInterface.client, botToken)
-	token.LockForToken(bot.RedisInterface.client, botToken)
+	token.WaitForToken(bot.RedisInterface.client, cfg.Discord.Token)
+	token.LockForToken(bot.RedisInterface.client, cfg.Discord.Token)
 	// Open a websocket connection to Discord and begin listening.
 	err = dg.Open()
 	if err != nil {
-		log.Println("Could not connect Bot to the Discord Servers with error:", err)
+		logging.Default().Error("Couldn't connect to Discord", "err", err)
 		return nil
 	}
 
 	rediskey.SetVersionAndCommit(context.Background(), bot.RedisInterface.client, version, commit)
 
-	nodeID := os.Getenv("SCW_NODE_ID")
-	go metrics.PrometheusMetricsServer(bot.RedisInterface.client, nodeID, "2112")
+	go metrics.PrometheusMetricsServer(bot.RedisInterface.client, cfg.NodeID, "2112")
 
-	go metrics.StartHealthCheckServer("8080")
+	go metrics.StartHealthCheckServer("8080", bot.healthChecker())
 
-	log.Println("Finished identifying to the Discord API. Now ready for incoming events")
+	logging.Default().Info("Finished identifying to the Discord API. Now ready for incoming events")
 
-	listeningTo := os.Getenv("AUTOMUTEUS_LISTENING")
-	if listeningTo == "" {
-		listeningTo = "/help"
-	}
-
-	status := &discordgo.UpdateStatusData{
-		IdleSince: nil,
-		Activities: []*discordgo.Activity{&discordgo.Activity{
-			Name: listeningTo,
-			Type: discordgo.ActivityTypeListening,
-		}},
-		AFK:    false,
-		Status: "",
-	}
-	err = dg.UpdateStatusComplex(*status)
-	if err != nil {
-		log.Println(err)
-	}
+	go bot.maintenanceWorker()
+	go bot.debugGuildsWorker()
 
 	// indicate to Kubernetes that we're ready to start receiving traffic
 	metrics.GlobalReady = true
 
-	if topGGToken != "" {
-		dblClient, err := dbl.NewClient(topGGToken)
+	if cfg.TopGG.Token != "" {
+		dblClient, err := dbl.NewClient(cfg.TopGG.Token)
 		if err != nil {
-			log.Println("Error creating Top.gg client: ", err)
+			logging.Default().Error("Couldn't create the Top.gg client", "err", err)
 		}
 		bot.TopGGClient = dblClient
 	} else {
-		log.Println("No TOP_GG_TOKEN provided")
+		logging.Default().Info("No TOP_GG_TOKEN provided")
 	}
 
 	// TODO this is ugly. Should make a proper cronjob to refresh the stats regularly
 	go bot.statsRefreshWorker(rediskey.TotalUsersExpiration)
 
+	go bot.shardStatusWorker()
+	go bot.ownerMessageWorker()
+	go bot.profilesChangedWorker()
+
 	return &bot
 }
 
@@ -160,13 +175,13 @@ func (bot *Bot) statsRefreshWorker(dur time.Duration) {
 	for {
 		users := rediskey.GetTotalUsers(context.Background(), bot.RedisInterface.client)
 		if users == rediskey.NotFound {
-			log.Println("Refreshing user stats with worker")
+			logging.Default().Debug("Refreshing user stats with worker")
 			rediskey.RefreshTotalUsers(context.Background(), bot.RedisInterface.client, bot.PostgresInterface.Pool)
 		}
 
 		games := rediskey.GetTotalGames(context.Background(), bot.RedisInterface.client)
 		if games == rediskey.NotFound {
-			log.Println("Refreshing game stats with worker")
+			logging.Default().Debug("Refreshing game stats with worker")
 			rediskey.RefreshTotalGames(context.Background(), bot.RedisInterface.client, bot.PostgresInterface.Pool)
 		}
 
@@ -175,6 +190,7 @@ func (bot *Bot) statsRefreshWorker(dur time.Duration) {
 }
 
 func (bot *Bot) Close() {
+	bot.RedisInterface.RemoveShardStatus(bot.PrimarySession.ShardID)
 	bot.PrimarySession.Close()
 	bot.RedisInterface.Close()
 	bot.StorageInterface.Close()
@@ -184,29 +200,31 @@ var EmojiLock = sync.Mutex{}
 var AllEmojisStartup []*discordgo.Emoji = nil
 
 func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *discordgo.GuildCreate) {
+	emojiGuildConfigured := emojiGuildID != ""
 	return func(s *discordgo.Session, m *discordgo.GuildCreate) {
+		logger := guildLogger(m.Guild.ID)
 		gid, err := strconv.ParseUint(m.Guild.ID, 10, 64)
 		if err != nil {
-			log.Println(err)
+			logger.Error("Couldn't parse the guild ID", "err", err)
 		}
 
 		go func() {
 			guild, err := bot.PostgresInterface.EnsureGuildExists(gid, m.Guild.Name)
 			if err != nil {
-				log.Println(err)
+				logger.Error("Couldn't add the guild to Postgres", "err", err)
 			} else if guild != nil {
 				err = bot.GalactusClient.VerifyPremiumMembership(guild.GuildID, premium.Tier(guild.Premium))
 				if err != nil {
-					log.Println(err)
+					logger.Error("Couldn't verify the guild's premium membership", "err", err)
 				}
 			}
 		}()
 
-		log.Printf("Added to new Guild, id %s, name %s", m.Guild.ID, m.Guild.Name)
+		logger.Info("Added to new Guild", "name", m.Guild.Name)
 		bot.RedisInterface.AddUniqueGuildCounter(m.Guild.ID)
 
 		if emojiGuildID == "" {
-			log.Println("[This is not an error] No explicit guildID provided for emojis; using the current guild default")
+			logger.Debug("No explicit guildID provided for emojis; using the current guild default")
 			emojiGuildID = m.Guild.ID
 		}
 
@@ -215,15 +233,15 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 		if AllEmojisStartup == nil {
 			allEmojis, err := s.GuildEmojis(emojiGuildID)
 			if err != nil {
-				log.Println(err)
+				logger.Error("Couldn't fetch the emojis", "emojiGuild", emojiGuildID, "err", err)
 			} else {
 				bot.addAllMissingEmojis(s, m.Guild.ID, true, allEmojis)
 				bot.addAllMissingEmojis(s, m.Guild.ID, false, allEmojis)
 
 				// if we specified the guild ID, then any subsequent guilds should just use the existing emojis
-				if os.Getenv("EMOJI_GUILD_ID") != "" {
+				if emojiGuildConfigured {
 					AllEmojisStartup = allEmojis
-					log.Println("Skipping subsequent guilds; emojis added successfully")
+					logger.Debug("Skipping subsequent guilds; emojis added successfully")
 				}
 			}
 		} else {
@@ -245,7 +263,7 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 				lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
 			}
 			if dgs != nil && dgs.ConnectCode != "" {
-				log.Println("Resubscribing to Redis events for an old game: " + connCode)
+				dgs.logger().Info("Resubscribing to Redis events for an old game")
 				killChan := make(chan EndGameMessage)
 				go bot.SubscribeToGameByConnectCode(gsr.GuildID, dgs.ConnectCode, killChan)
 				dgs.Subscribed = true
@@ -262,12 +280,13 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 }
 
 func (bot *Bot) leaveGuild(_ *discordgo.Session, m *discordgo.GuildDelete) {
-	log.Println("Bot was removed from Guild " + m.ID)
+	logger := guildLogger(m.ID)
+	logger.Info("Bot was removed from the guild")
 	bot.RedisInterface.LeaveUniqueGuildCounter(m.ID)
 
 	err := bot.StorageInterface.DeleteGuildSettings(m.ID)
 	if err != nil {
-		log.Println(err)
+		logger.Error("Couldn't delete the guild's settings", "err", err)
 	}
 }
 
@@ -287,17 +306,20 @@ func (bot *Bot) forceEndGame(gsr GameStateRequest) {
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
 	bot.RedisInterface.RemoveOldGame(dgs.GuildID, dgs.ConnectCode)
+	bot.RedisInterface.RevokeCaptureLink(dgs.ConnectCode)
+	// free up the code immediately, rather than waiting for it to expire; personal codes are reused by the next game
+	bot.RedisInterface.RemoveActiveConnectCode(dgs.ConnectCode)
 
 	// Note, this shouldn't be necessary with the TTL of the keys, but it can't hurt to clean up...
 	bot.RedisInterface.DeleteDiscordGameState(dgs)
 }
 
 func MessageDeleteWorker(s *discordgo.Session, msgChannelID, msgID string, waitDur time.Duration) {
-	log.Printf("Message worker is sleeping for %s before deleting message", waitDur.String())
+	logging.Default().Debug("Message worker is sleeping before deleting message", "wait", waitDur.String())
 	time.Sleep(waitDur)
 	err := s.ChannelMessageDelete(msgChannelID, msgID)
 	if err != nil {
-		log.Println(err)
+		logging.Default().Warn("Couldn't delete the message", "channel", msgChannelID, "err", err)
 	}
 }
 
@@ -317,7 +339,7 @@ func (bot *Bot) RefreshGameStateMessage(gsr GameStateRequest, sett *settings.Gui
 	}
 
 	deleted := dgs.DeleteGameStateMsg(bot.PrimarySession, false) // delete the old message
-	created := dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), dgs.GameStateMsg.MessageChannelID, dgs.GameStateMsg.LeaderID)
+	created := dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), dgs.GameStateMsg.MessageChannelID, dgs.GameStateMsg.LeaderID, sett)
 
 	if deleted && created {
 		go metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 2)
@@ -367,7 +389,7 @@ func linkPlayer(redis *RedisInterface, dgs *GameState, userID, color string) (co
 		if foundID != "" {
 			err := redis.AddUsernameLink(dgs.GuildID, userID, auData.Name)
 			if err != nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't save the username link", "user", userID, "err", err)
 			}
 			return command.LinkSuccess, nil
 		} else {
@@ -401,19 +423,27 @@ func getTrackingChannel(guild *discordgo.Guild, userID string) string {
 	return ""
 }
 
-func (bot *Bot) newGame(dgs *GameState) (_ command.NewStatus, activeGames int64) {
+// newGame resets or initializes the game state for a new game. If personalCode is provided and not already in use, it's
+// used as the game's connect code so the host's capture can reconnect without a new link
+func (bot *Bot) newGame(dgs *GameState, personalCode string) (_ command.NewStatus, activeGames int64) {
+	// the game being replaced hands its code over if it's the host's personal code, instead of freeing it after the new
+	// game already took it
+	handover := personalCode != "" && dgs.ConnectCode == personalCode
 	if dgs.GameStateMsg.Exists() {
 		if v, ok := bot.EndGameChannels[dgs.ConnectCode]; ok {
-			v <- true
+			v <- EndGameMessage(!handover)
 		}
 		delete(bot.EndGameChannels, dgs.ConnectCode)
 
+		if handover && dgs.DeleteGameStateMsg(bot.PrimarySession, true) {
+			go metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+		}
 		dgs.Reset()
 	} else {
 		premStatus, days, err := bot.PostgresInterface.GetGuildOrUserPremiumStatus(
 			bot.official, bot.TopGGClient, dgs.GuildID, dgs.GameStateMsg.LeaderID)
 		if err != nil {
-			log.Println("Error in /newgame get premium:", err)
+			dgs.logger().Error("Couldn't get the premium status for /new", "err", err)
 		}
 		premTier := premium.FreeTier
 		if !premium.IsExpired(premStatus, days) {
@@ -429,7 +459,14 @@ func (bot *Bot) newGame(dgs *GameState) (_ command.NewStatus, activeGames int64)
 		}
 	}
 
-	dgs.ConnectCode = generateConnectCode(dgs.GuildID)
+	if personalCode != "" && (handover || !bot.RedisInterface.IsActiveConnectCode(personalCode)) {
+		dgs.ConnectCode = personalCode
+	} else {
+		dgs.ConnectCode = bot.generateConnectCode()
+	}
+	if dgs.ConnectCode == "" {
+		return command.NewConnectCodeError, activeGames
+	}
 	dgs.Subscribed = true
 
 	return command.NewSuccess, activeGames
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// MaxAutocompleteChoices is the most choices Discord will show for an autocompleted option
+const MaxAutocompleteChoices = 25
+
+// GetFocusedOption returns the option the user is typing in, or nil if there isn't one
+func GetFocusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+		if focused := GetFocusedOption(opt.Options); focused != nil {
+			return focused
+		}
+	}
+	return nil
+}
+
+func AutocompleteResponse(choices []*discordgo.ApplicationCommandOptionChoice) *discordgo.InteractionResponse {
+	if len(choices) > MaxAutocompleteChoices {
+		choices = choices[:MaxAutocompleteChoices]
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	}
+}
+
+// PlayerColorChoice shows the player's name next to their color
+func PlayerColorChoice(color, playerName string) *discordgo.ApplicationCommandOptionChoice {
+	return &discordgo.ApplicationCommandOptionChoice{
+		Name:  fmt.Sprintf("%s (%s)", color, playerName),
+		Value: color,
+	}
+}
+
+// MatchIDChoice shows when the match was played and who won next to its ID
+func MatchIDChoice(matchID string, startTime int64, winType game.GameResult, sett *settings.GuildSettings) *discordgo.ApplicationCommandOptionChoice {
+	var winner string
+	switch winType {
+	case game.HumansByVote, game.HumansByTask, game.HumansDisconnect:
+		winner = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.stats.autocomplete.crewmates",
+			Other: "Crewmates won",
+		})
+	case game.ImpostorByVote, game.ImpostorByKill, game.ImpostorBySabotage, game.ImpostorDisconnect:
+		winner = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.stats.autocomplete.imposters",
+			Other: "Imposters won",
+		})
+	default:
+		winner = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.stats.autocomplete.unknown",
+			Other: "Unknown winner",
+		})
+	}
+	return &discordgo.ApplicationCommandOptionChoice{
+		Name:  fmt.Sprintf("%s · %s · %s", matchID, time.Unix(startTime, 0).UTC().Format("2006-01-02 15:04"), winner),
+		Value: matchID,
+	}
+}
@@ -21,11 +21,14 @@ const (
 var All = []*discordgo.ApplicationCommand{
 	&Help,
 	&New,
+	&PersonalLink,
 	&Refresh,
 	&Pause,
 	&End,
 	&Link,
 	&Unlink,
+	&Profile,
+	&Links,
 	&Settings,
 	&Privacy,
 	&Info,
@@ -79,7 +82,7 @@ func localizeCommandDescription(cmd *discordgo.ApplicationCommand, sett *setting
 	})
 }
 
-// TODO supplement these embed with more detail than just the command description
+// constructEmbedForCommand is the top of a command's help; helpPages has the details for the fields
 func constructEmbedForCommand(
 	cmd *discordgo.ApplicationCommand,
 	sett *settings.GuildSettings,
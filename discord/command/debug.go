@@ -2,18 +2,26 @@ package command
 
 import (
 	"fmt"
+	"github.com/automuteus/automuteus/amongus"
 	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/job"
 	"github.com/automuteus/utils/pkg/discord"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"strings"
 )
 
 const (
-	User      = "user"
-	GameState = "game-state"
+	User        = "user"
+	GameState   = "game-state"
+	Jobs        = "jobs"
+	NameMatches = "name-matches"
 )
 
+// maxDebugJobsLength keeps the job listing (plus the surrounding text) under Discord's 2000 character message limit
+const maxDebugJobsLength = 1800
+
 var Debug = discordgo.ApplicationCommand{
 	Name:        "debug",
 	Description: "View and clear debug information for AutoMuteUs",
@@ -41,6 +49,16 @@ var Debug = discordgo.ApplicationCommand{
 					Description: "Game State",
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 				},
+				{
+					Name:        Jobs,
+					Description: "Recent and failed capture events",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        NameMatches,
+					Description: "Recent attempts to link players by matching names",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
 			},
 		},
 		{
@@ -147,3 +165,80 @@ func DebugResponse(operationType string, cached map[string]interface{}, stateByt
 		},
 	}
 }
+
+// DebugJobsResponse lists the most recent capture events for the game, and the ones that failed, most recent first
+func DebugJobsResponse(history, failures []job.Record, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	var content string
+	if len(history) == 0 && len(failures) == 0 {
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.debug.view.jobs.empty",
+			Other: "I haven't received any capture events for this game recently",
+		})
+	} else {
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.debug.view.jobs.success",
+			Other: "Recent capture events:\n```\n{{.History}}\n```\nFailed capture events:\n```\n{{.Failures}}\n```",
+		}, map[string]interface{}{
+			"History":  formatJobRecords(history, false, maxDebugJobsLength/2),
+			"Failures": formatJobRecords(failures, true, maxDebugJobsLength/2),
+		})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+		},
+	}
+}
+
+func formatJobRecords(records []job.Record, withPayload bool, maxLength int) string {
+	if len(records) == 0 {
+		return "-"
+	}
+	buf := strings.Builder{}
+	for _, record := range records {
+		line := record.String()
+		if withPayload {
+			line += "\n  " + strings.ReplaceAll(record.Payload, "`", "'")
+		}
+		if buf.Len()+len(line)+1 > maxLength {
+			break
+		}
+		buf.WriteString(line + "\n")
+	}
+	return buf.String()
+}
+
+// DebugNameMatchesResponse lists the most recent name matching decisions for the game, most recent first
+func DebugNameMatchesResponse(decisions []amongus.NameMatchDecision, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	var content string
+	if len(decisions) == 0 {
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.debug.view.name-matches.empty",
+			Other: "I haven't tried to match any player names for this game recently",
+		})
+	} else {
+		buf := strings.Builder{}
+		for _, decision := range decisions {
+			line := decision.String()
+			if buf.Len()+len(line)+1 > maxDebugJobsLength {
+				break
+			}
+			buf.WriteString(line + "\n")
+		}
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.debug.view.name-matches.success",
+			Other: "Recent name matches:\n```\n{{.Decisions}}\n```",
+		}, map[string]interface{}{
+			"Decisions": buf.String(),
+		})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+		},
+	}
+}
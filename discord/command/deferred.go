@@ -0,0 +1,67 @@
+package command
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Deferred are the commands that can take longer than the 3 seconds Discord gives us to respond, mapped to whether
+// their responses are usually private. Discord is told up front to wait for them, and the response is edited in when
+// it's ready; a response that's more or less private than that is sent as a follow-up instead. The commands that
+// lock the game state or mute through Galactus are here too, since both can take a few seconds
+var Deferred = map[string]bool{
+	New.Name:          true,
+	PersonalLink.Name: true,
+	Links.Name:        true,
+	Privacy.Name:      true,
+	Stats.Name:        false,
+	Premium.Name:      false,
+	Owner.Name:        true,
+	Link.Name:         true,
+	Unlink.Name:       true,
+	LinkUser.Name:     true,
+	UnlinkUser.Name:   true,
+	Refresh.Name:      true,
+	Pause.Name:        true,
+	End.Name:          true,
+	Debug.Name:        true,
+}
+
+// IsPrivate is whether only the user who used the command can see the response
+func IsPrivate(resp *discordgo.InteractionResponse) bool {
+	return resp.Data != nil && resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+}
+
+// DeferredEdit is the edit that replaces a deferred response with resp, or updates the message of a deferred
+// component. It's only valid if resp is as private as the deferred response was. Components and embeds that resp
+// doesn't set are left alone
+func DeferredEdit(resp *discordgo.InteractionResponse) *discordgo.WebhookEdit {
+	content := resp.Data.Content
+	if content == "" && len(resp.Data.Embeds) == 0 {
+		content = "\u200b"
+	}
+	edit := &discordgo.WebhookEdit{
+		Content:         &content,
+		Files:           resp.Data.Files,
+		AllowedMentions: resp.Data.AllowedMentions,
+	}
+	if resp.Data.Components != nil {
+		edit.Components = &resp.Data.Components
+	}
+	if resp.Data.Embeds != nil {
+		edit.Embeds = &resp.Data.Embeds
+	}
+	return edit
+}
+
+// DeferredFollowup is resp as a follow-up message, keeping whether it's private, for when the deferred response
+// was the other way around and has to be deleted
+func DeferredFollowup(resp *discordgo.InteractionResponse) *discordgo.WebhookParams {
+	return &discordgo.WebhookParams{
+		Content:         resp.Data.Content,
+		Components:      resp.Data.Components,
+		Embeds:          resp.Data.Embeds,
+		Files:           resp.Data.Files,
+		AllowedMentions: resp.Data.AllowedMentions,
+		Flags:           resp.Data.Flags & discordgo.MessageFlagsEphemeral,
+	}
+}
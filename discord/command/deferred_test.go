@@ -0,0 +1,54 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDeferredResponses(t *testing.T) {
+	private := PrivateResponse("only you can see this")
+	public := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{Title: "everyone can see this"}},
+		},
+	}
+	if !IsPrivate(private) || IsPrivate(public) {
+		t.Error("expected private responses to be told apart from public ones")
+	}
+
+	if followup := DeferredFollowup(private); followup.Flags != discordgo.MessageFlagsEphemeral || followup.Content != "only you can see this" {
+		t.Errorf("expected a private follow-up to stay private, got %+v", followup)
+	}
+	if followup := DeferredFollowup(public); followup.Flags != 0 || len(followup.Embeds) != 1 {
+		t.Errorf("expected a public follow-up to stay public, got %+v", followup)
+	}
+
+	if edit := DeferredEdit(public); *edit.Content != "" || len(*edit.Embeds) != 1 {
+		t.Errorf("expected an edit with only embeds to keep them, got %+v", edit)
+	}
+	if edit := DeferredEdit(PrivateResponse("")); *edit.Content != "\u200b" {
+		t.Errorf("expected an empty edit to get placeholder content, got %q", *edit.Content)
+	}
+	update := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "done",
+			Components: []discordgo.MessageComponent{},
+		},
+	}
+	if edit := DeferredEdit(update); edit.Embeds != nil || edit.Components == nil || len(*edit.Components) != 0 {
+		t.Errorf("expected an update to remove the components and leave the embeds alone, got %+v", edit)
+	}
+
+	sett := settings.MakeGuildSettings()
+	// the responses a deferred command can give that aren't as private as it's deferred
+	if !Deferred[New.Name] || IsPrivate(NewResponse(NewLockout, NewInfo{}, sett)) == Deferred[New.Name] {
+		t.Error("expected the /new lockout message to be public, unlike the rest of /new")
+	}
+	if Deferred[Stats.Name] || IsPrivate(InsufficientPermissionsResponse(sett)) == Deferred[Stats.Name] {
+		t.Error("expected permission errors to be private, unlike the rest of /stats")
+	}
+}
@@ -1,20 +1,47 @@
 package command
 
 import (
+	"github.com/automuteus/utils/pkg/locale"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
+var dmMessage = &i18n.Message{
+	ID: "commands.dm.guildonly",
+	Other: "Sorry, that only works in a server. Please execute the command in a text channel instead.\n" +
+		"In DMs, I can only do `/help`, `/info`, `/privacy` and `/stats view me`.",
+}
+
+// dmLegacyMessage is what dmMessage replaced. It's still used in the languages that haven't translated dmMessage
+// yet, so they don't get English instead
+var dmLegacyMessage = &i18n.Message{
+	ID:    "commands.dm",
+	Other: "Sorry, I don't respond to DMs. Please execute the command in a text channel instead.",
+}
+
+// DmResponse refuses commands that need a guild when they're used in DMs
 func DmResponse(sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	message := dmMessage
+	if translatedFirst(sett.GetLanguage(), dmLegacyMessage.ID, dmMessage.ID) {
+		message = dmLegacyMessage
+	}
 	return &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: sett.LocalizeMessage(&i18n.Message{
-				ID: "commands.dm",
-				Other: "Sorry, I don't respond to DMs. " +
-					"Please execute the command in a text channel instead.",
-			}),
+			Content: sett.LocalizeMessage(message),
 		},
 	}
 }
+
+// translatedFirst is whether the legacy message is translated into a language that's preferred over the languages
+// the message is translated into
+func translatedFirst(lang, legacyID, id string) bool {
+	localizer := i18n.NewLocalizer(locale.GetBundle(), lang)
+	_, legacyTag, err := localizer.LocalizeWithTag(&i18n.LocalizeConfig{MessageID: legacyID})
+	if err != nil {
+		return false
+	}
+	_, tag, err := localizer.LocalizeWithTag(&i18n.LocalizeConfig{MessageID: id})
+	return err != nil || tag != legacyTag
+}
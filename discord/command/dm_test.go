@@ -0,0 +1,23 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/automuteus/utils/pkg/locale"
+	"github.com/automuteus/utils/pkg/settings"
+)
+
+func TestDmResponse(t *testing.T) {
+	locale.InitLang("../../locales", "en")
+	defer locale.InitLang("", "")
+
+	sett := settings.MakeGuildSettings()
+	if content := DmResponse(sett).Data.Content; !strings.Contains(content, "/stats view me") {
+		t.Errorf("expected the new message in English, got %q", content)
+	}
+	sett.Language = "fr"
+	if content := DmResponse(sett).Data.Content; !strings.Contains(content, "Désolé") {
+		t.Errorf("expected the old message to be used until it's translated, got %q", content)
+	}
+}
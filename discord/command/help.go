@@ -2,6 +2,10 @@ package command
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -21,6 +25,10 @@ var Help = discordgo.ApplicationCommand{
 					Name:  New.Name,
 					Value: New.Name,
 				},
+				{
+					Name:  PersonalLink.Name,
+					Value: PersonalLink.Name,
+				},
 				{
 					Name:  Refresh.Name,
 					Value: Refresh.Name,
@@ -41,6 +49,14 @@ var Help = discordgo.ApplicationCommand{
 					Name:  Unlink.Name,
 					Value: Unlink.Name,
 				},
+				{
+					Name:  Profile.Name,
+					Value: Profile.Name,
+				},
+				{
+					Name:  Links.Name,
+					Value: Links.Name,
+				},
 				{
 					Name:  Settings.Name,
 					Value: Settings.Name,
@@ -71,25 +87,290 @@ var Help = discordgo.ApplicationCommand{
 	},
 }
 
-func HelpResponse(sett *settings.GuildSettings, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionResponse {
+const (
+	helpIDPrefix = "help:"
+	// leaves room under Discord's limits of 25 fields and 6000 characters per embed
+	helpFieldsPerPage = 6
+	helpPageLength    = 4000
+	maxFieldLength    = 1024
+)
+
+// helpExamples are usage examples for the commands, shown on the first page of their help
+var helpExamples = map[string]*i18n.Message{
+	New.Name: {
+		ID:    "commands.help.examples.new",
+		Other: "`/new` starts a game in your voice channel and sends you the link for your capture",
+	},
+	PersonalLink.Name: {
+		ID:    "commands.help.examples.personal-link",
+		Other: "`/personal-link show` gets a capture link that works for every game you host",
+	},
+	Link.Name: {
+		ID:    "commands.help.examples.link",
+		Other: "`/link user:@Alice color:red` links Alice to the red player in the current game",
+	},
+	Unlink.Name: {
+		ID:    "commands.help.examples.unlink",
+		Other: "`/unlink user:@Alice` unlinks Alice from her player",
+	},
+	Profile.Name: {
+		ID:    "commands.help.examples.profile",
+		Other: "`/profile set names:Alice,Ali color:red` links you automatically when you play as Alice or Ali",
+	},
+	Links.Name: {
+		ID:    "commands.help.examples.links",
+		Other: "`/links list` shows the in-game names I remember you by",
+	},
+	Settings.Name: {
+		ID:    "commands.help.examples.settings",
+		Other: "`/settings language` shows the languages I speak\n`/settings delays` shows the mute delays between phases",
+	},
+	Privacy.Name: {
+		ID:    "commands.help.examples.privacy",
+		Other: "`/privacy show-me` shows everything I store about you",
+	},
+	Map.Name: {
+		ID:    "commands.help.examples.map",
+		Other: "`/map map_name:Skeld detailed:True` shows a detailed map of The Skeld",
+	},
+	Stats.Name: {
+		ID:    "commands.help.examples.stats",
+		Other: "`/stats view user user:@Alice` shows Alice's stats in this server\n`/stats view me` shows your stats across every server",
+	},
+}
+
+func HelpResponse(sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionResponse {
 	if len(options) > 0 {
-		cmd := getCommand(options[0].StringValue())
-		embed := constructEmbedForCommand(cmd, sett)
-		return &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{embed},
-			},
+		if cmd := getCommand(options[0].StringValue()); cmd != nil {
+			return &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: helpPageData(cmd, 0, sett, ext),
+			}
+		}
+	}
+	m := HelpEmbedResponse(All, sett)
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:  1 << 6, // private, so it's in the user's language
+			Embeds: []*discordgo.MessageEmbed{&m},
+		},
+	}
+}
+
+// IsHelpPageCustomID returns true for the buttons that turn the pages of a command's help
+func IsHelpPageCustomID(customID string) bool {
+	return strings.HasPrefix(customID, helpIDPrefix)
+}
+
+// HelpPageResponse turns the help message to the page the button was for
+func HelpPageResponse(customID string, sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings) *discordgo.InteractionResponse {
+	name, page, ok := parseHelpCustomID(customID)
+	if !ok {
+		return nil
+	}
+	cmd := getCommand(name)
+	if cmd == nil {
+		return nil
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: helpPageData(cmd, page, sett, ext),
+	}
+}
+
+func helpCustomID(name string, page int) string {
+	return fmt.Sprintf("%s%s:%d", helpIDPrefix, name, page)
+}
+
+func parseHelpCustomID(customID string) (name string, page int, ok bool) {
+	tokens := strings.Split(strings.TrimPrefix(customID, helpIDPrefix), ":")
+	if len(tokens) != 2 {
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return tokens[0], page, true
+}
+
+func helpPageData(cmd *discordgo.ApplicationCommand, page int, sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings) *discordgo.InteractionResponseData {
+	pages := helpPages(cmd, sett, ext)
+	if page < 0 {
+		page = 0
+	} else if page >= len(pages) {
+		page = len(pages) - 1
+	}
+	embed := constructEmbedForCommand(cmd, sett)
+	embed.Fields = pages[page]
+
+	data := &discordgo.InteractionResponseData{
+		Flags:  1 << 6, // private, so it's in the user's language
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}
+	if len(pages) > 1 {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.page",
+				Other: "Page {{.Page}}/{{.Pages}}",
+			}, map[string]interface{}{
+				"Page":  page + 1,
+				"Pages": len(pages),
+			}),
 		}
-	} else {
-		m := HelpEmbedResponse(All, sett)
-		return &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{&m},
+		data.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label: sett.LocalizeMessage(&i18n.Message{
+							ID:    "commands.help.button.previous",
+							Other: "Previous",
+						}),
+						Style:    discordgo.SecondaryButton,
+						CustomID: helpCustomID(cmd.Name, page-1),
+						Disabled: page == 0,
+						Emoji:    discordgo.ComponentEmoji{Name: "◀️"},
+					},
+					discordgo.Button{
+						Label: sett.LocalizeMessage(&i18n.Message{
+							ID:    "commands.help.button.next",
+							Other: "Next",
+						}),
+						Style:    discordgo.SecondaryButton,
+						CustomID: helpCustomID(cmd.Name, page+1),
+						Disabled: page == len(pages)-1,
+						Emoji:    discordgo.ComponentEmoji{Name: "▶️"},
+					},
+				},
 			},
 		}
 	}
+	return data
+}
+
+// helpPages splits the fields describing the command into pages that fit in an embed. There's always at least one
+func helpPages(cmd *discordgo.ApplicationCommand, sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings) [][]*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.permissions",
+				Other: "Who can use it",
+			}),
+			Value: EffectivePermission(cmd.Name, ext, sett),
+		},
+	}
+	if example, ok := helpExamples[cmd.Name]; ok {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.examples",
+				Other: "Examples",
+			}),
+			Value: sett.LocalizeMessage(example),
+		})
+	}
+	fields = append(fields, optionFields(cmd, sett)...)
+
+	var pages [][]*discordgo.MessageEmbedField
+	var page []*discordgo.MessageEmbedField
+	length := 0
+	for _, field := range fields {
+		fieldLength := len(field.Name) + len(field.Value)
+		if len(page) == helpFieldsPerPage || (len(page) > 0 && length+fieldLength > helpPageLength) {
+			pages = append(pages, page)
+			page, length = nil, 0
+		}
+		page = append(page, field)
+		length += fieldLength
+	}
+	return append(pages, page)
+}
+
+// optionFields describes each subcommand with its options, or the command's own options if it has no subcommands
+func optionFields(cmd *discordgo.ApplicationCommand, sett *settings.GuildSettings) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	var options []*discordgo.ApplicationCommandOption
+	for _, opt := range cmd.Options {
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionSubCommandGroup:
+			for _, sub := range opt.Options {
+				fields = append(fields, subcommandField(cmd.Name, []string{opt.Name, sub.Name}, sub, sett))
+			}
+		case discordgo.ApplicationCommandOptionSubCommand:
+			fields = append(fields, subcommandField(cmd.Name, []string{opt.Name}, opt, sett))
+		default:
+			options = append(options, opt)
+		}
+	}
+	if len(options) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.options",
+				Other: "Options",
+			}),
+			Value: truncateField(describeOptions("commands."+cmd.Name, options, sett)),
+		})
+	}
+	return fields
+}
+
+func subcommandField(name string, path []string, sub *discordgo.ApplicationCommandOption, sett *settings.GuildSettings) *discordgo.MessageEmbedField {
+	prefix := "commands." + name + "." + strings.Join(path, ".")
+	value := localizeOptionDescription(prefix, sub, sett)
+	if len(sub.Options) > 0 {
+		value += "\n" + describeOptions(prefix, sub.Options, sett)
+	}
+	return &discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("`/%s %s`", name, strings.Join(path, " ")),
+		Value: truncateField(value),
+	}
+}
+
+// describeOptions lists the options, one per line, with whether they're required and what they can be
+func describeOptions(prefix string, options []*discordgo.ApplicationCommandOption, sett *settings.GuildSettings) string {
+	lines := make([]string, len(options))
+	for i, opt := range options {
+		optPrefix := prefix + "." + opt.Name
+		line := fmt.Sprintf("`%s`: %s", opt.Name, localizeOptionDescription(optPrefix, opt, sett))
+		if opt.Required {
+			line += " " + sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.required",
+				Other: "(required)",
+			})
+		}
+		if len(opt.Choices) > 0 {
+			choices := make([]string, len(opt.Choices))
+			for j, choice := range opt.Choices {
+				choices[j] = "`" + sett.LocalizeMessage(&i18n.Message{
+					ID:    optPrefix + ".choices." + choice.Name,
+					Other: choice.Name,
+				}) + "`"
+			}
+			line += "\n  " + sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.help.choices",
+				Other: "One of: {{.Choices}}",
+			}, map[string]interface{}{
+				"Choices": strings.Join(choices, ", "),
+			})
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// uses the same IDs as the localizations registered with Discord
+func localizeOptionDescription(prefix string, opt *discordgo.ApplicationCommandOption, sett *settings.GuildSettings) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    prefix + ".description",
+		Other: opt.Description,
+	})
+}
+
+func truncateField(value string) string {
+	if runes := []rune(value); len(runes) > maxFieldLength {
+		return string(runes[:maxFieldLength-1]) + "…"
+	}
+	return value
 }
 
 func HelpEmbedResponse(commands []*discordgo.ApplicationCommand, sett *settings.GuildSettings) discordgo.MessageEmbed {
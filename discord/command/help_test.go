@@ -0,0 +1,89 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestHelpPagesFitInEmbeds(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	for _, cmd := range All {
+		pages := helpPages(cmd, sett, nil)
+		if len(pages) == 0 {
+			t.Errorf("Expected at least one help page for /%s", cmd.Name)
+		}
+		for i, page := range pages {
+			if len(page) == 0 || len(page) > helpFieldsPerPage {
+				t.Errorf("Expected 1 to %d fields on page %d of /%s, got %d", helpFieldsPerPage, i, cmd.Name, len(page))
+			}
+			length := 0
+			for _, field := range page {
+				if field.Value == "" || len([]rune(field.Value)) > maxFieldLength {
+					t.Errorf("Field %s of /%s has an invalid length of %d", field.Name, cmd.Name, len([]rune(field.Value)))
+				}
+				length += len(field.Name) + len(field.Value)
+			}
+			if length > 6000-1000 {
+				t.Errorf("Page %d of /%s is too long for an embed: %d", i, cmd.Name, length)
+			}
+		}
+	}
+}
+
+func TestHelpDescribesOptions(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	fields := optionFields(&Links, sett)
+	if len(fields) != 3 || fields[0].Name != "`/links list`" || fields[2].Name != "`/links add`" {
+		t.Fatalf("Expected a field for each /links subcommand, got %v", fields)
+	}
+	if fields[2].Value != "Link a user to an in-game name in future games (admin only)\n"+
+		"`user`: User to link (required)\n`name`: In-game name (required)" {
+		t.Errorf("Unexpected description for /links add: %s", fields[2].Value)
+	}
+
+	fields = optionFields(&Map, sett)
+	if len(fields) != 1 {
+		t.Fatalf("Expected the options of /map in one field, got %v", fields)
+	}
+}
+
+func TestHelpPagination(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	resp := HelpResponse(sett, nil, nil)
+	if len(resp.Data.Components) != 0 {
+		t.Error("Expected no buttons on the command overview")
+	}
+
+	pages := len(helpPages(&Settings, sett, nil))
+	if pages < 2 {
+		t.Fatalf("Expected /settings to need more than one page, got %d", pages)
+	}
+	data := helpPageData(&Settings, 0, sett, nil)
+	buttons := data.Components[0].(discordgo.ActionsRow).Components
+	previous, next := buttons[0].(discordgo.Button), buttons[1].(discordgo.Button)
+	if !previous.Disabled || next.Disabled {
+		t.Error("Expected only the next button to be enabled on the first page")
+	}
+
+	resp = HelpPageResponse(next.CustomID, sett, nil)
+	if resp == nil || resp.Type != discordgo.InteractionResponseUpdateMessage {
+		t.Fatal("Expected the next button to update the message")
+	}
+	if resp.Data.Embeds[0].Footer.Text != fmt.Sprintf("Page 2/%d", pages) {
+		t.Errorf("Expected to be on page 2, got %s", resp.Data.Embeds[0].Footer.Text)
+	}
+
+	// out of range pages are clamped
+	data = helpPageData(&Settings, pages+5, sett, nil)
+	buttons = data.Components[0].(discordgo.ActionsRow).Components
+	if !buttons[1].(discordgo.Button).Disabled {
+		t.Error("Expected the next button to be disabled on the last page")
+	}
+
+	if HelpPageResponse("help:nonexistent:1", sett, nil) != nil || HelpPageResponse("help:settings", sett, nil) != nil {
+		t.Error("Expected invalid custom IDs to be ignored")
+	}
+}
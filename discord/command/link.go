@@ -31,7 +31,8 @@ var Link = discordgo.ApplicationCommand{
 			Name:        "color",
 			Description: "In-game color",
 			Required:    true,
-			Choices:     colorsToCommandChoices(),
+			// filled in with the players who aren't linked yet
+			Autocomplete: true,
 		},
 	},
 }
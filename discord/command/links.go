@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	LinksList   = "list"
+	LinksRemove = "remove"
+	LinksAdd    = "add"
+)
+
+type LinksStatus int
+
+const (
+	LinksSuccess LinksStatus = iota
+	LinksNotFound
+	LinksInvalidName
+)
+
+var Links = discordgo.ApplicationCommand{
+	Name:        "links",
+	Description: "View and fix the in-game names I remember users by",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        LinksList,
+			Description: "List the in-game names I remember you (or another user) by",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "user",
+					Description: "User whose names you want to list",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        LinksRemove,
+			Description: "Stop linking you (or another user, for admins) to an in-game name",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "name",
+					Description: "In-game name to forget",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        "user",
+					Description: "User to forget the name for",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        LinksAdd,
+			Description: "Link a user to an in-game name in future games (admin only)",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "user",
+					Description: "User to link",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    true,
+				},
+				{
+					Name:        "name",
+					Description: "In-game name",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// GetLinksParams returns the subcommand, the user it's for (userID if none was given) and the in-game name, if any
+func GetLinksParams(s *discordgo.Session, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) (action, targetID, name string) {
+	action = options[0].Name
+	targetID = userID
+	for _, opt := range options[0].Options {
+		switch opt.Name {
+		case "user":
+			targetID = opt.UserValue(s).ID
+		case "name":
+			name = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	return action, targetID, name
+}
+
+// ValidLinkName returns true if the name could be an in-game name
+func ValidLinkName(name string) bool {
+	return name != "" && len([]rune(name)) <= amongus.MaxInGameNameLength
+}
+
+// LinksResponse responds to a links subcommand. For LinksList, links maps each of the user's in-game names to the
+// other users who are also linked to it
+func LinksResponse(action string, status LinksStatus, userID, name string, links map[string][]string, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Links.Name, err, sett)
+	}
+	var content string
+	switch status {
+	case LinksNotFound:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.links.notfound",
+			Other: "{{.User}} isn't linked to the name `{{.Name}}`",
+		}, map[string]interface{}{
+			"User": discord.MentionByUserID(userID),
+			"Name": name,
+		})
+	case LinksInvalidName:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.links.invalidname",
+			Other: "In-game names can't be empty or longer than {{.Length}} characters",
+		}, map[string]interface{}{
+			"Length": amongus.MaxInGameNameLength,
+		})
+	case LinksSuccess:
+		switch action {
+		case LinksList:
+			if len(links) == 0 {
+				content = sett.LocalizeMessage(&i18n.Message{
+					ID:    "commands.links.list.empty",
+					Other: "I don't remember {{.User}} by any in-game names",
+				}, map[string]interface{}{
+					"User": discord.MentionByUserID(userID),
+				})
+			} else {
+				content = sett.LocalizeMessage(&i18n.Message{
+					ID:    "commands.links.list.success",
+					Other: "I remember {{.User}} by these in-game names:\n{{.Names}}",
+				}, map[string]interface{}{
+					"User":  discord.MentionByUserID(userID),
+					"Names": formatLinks(links, sett),
+				})
+			}
+		case LinksRemove:
+			content = sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.links.remove.success",
+				Other: "I won't link {{.User}} to the name `{{.Name}}` anymore",
+			}, map[string]interface{}{
+				"User": discord.MentionByUserID(userID),
+				"Name": name,
+			})
+		case LinksAdd:
+			content = sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.links.add.success",
+				Other: "I'll link {{.User}} when they play as `{{.Name}}`",
+			}, map[string]interface{}{
+				"User": discord.MentionByUserID(userID),
+				"Name": name,
+			})
+		}
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+			// don't ping the users we list
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}
+}
+
+func formatLinks(links map[string][]string, sett *settings.GuildSettings) string {
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := strings.Builder{}
+	for _, name := range names {
+		buf.WriteString(fmt.Sprintf("`%s`", name))
+		if others := links[name]; len(others) > 0 {
+			mentions := make([]string, len(others))
+			for i, other := range others {
+				mentions[i] = discord.MentionByUserID(other)
+			}
+			buf.WriteString(" " + sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.links.list.shared",
+				Other: "(also linked to {{.Users}})",
+			}, map[string]interface{}{
+				"Users": strings.Join(mentions, ", "),
+			}))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
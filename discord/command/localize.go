@@ -0,0 +1,154 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// discordLocales are the locales Discord supports that the bot might have translations for, by the language of the
+// locale file. Discord has no locale for the rest
+var discordLocales = map[string]discordgo.Locale{
+	"cs": discordgo.Czech,
+	"da": discordgo.Danish,
+	"de": discordgo.German,
+	"el": discordgo.Greek,
+	"es": discordgo.SpanishES,
+	"fi": discordgo.Finnish,
+	"fr": discordgo.French,
+	"hu": discordgo.Hungarian,
+	"it": discordgo.Italian,
+	"ja": discordgo.Japanese,
+	"ko": discordgo.Korean,
+	"nl": discordgo.Dutch,
+	"no": discordgo.Norwegian,
+	"pl": discordgo.Polish,
+	"pt": discordgo.PortugueseBR,
+	"ro": discordgo.Romanian,
+	"ru": discordgo.Russian,
+	"sv": discordgo.Swedish,
+	"tr": discordgo.Turkish,
+	"uk": discordgo.Ukrainian,
+	"vi": discordgo.Vietnamese,
+	"zh": discordgo.ChineseCN,
+}
+
+// Discord rejects the whole registration if any name or description breaks its rules, so bad translations are skipped
+var (
+	chatNameRegex = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+	slugRegex     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type commandLocalizer struct {
+	locale    discordgo.Locale
+	tag       language.Tag
+	localizer *i18n.Localizer
+}
+
+// Localized returns copies of the commands with the names and descriptions of the commands, their options and their
+// choices translated into every language in the bundle that Discord supports.
+// The message IDs are "commands.<command>[.<option>...].name" and ".description", as used by
+// localizeCommandDescription, and "commands.<command>[.<option>...].choices.<choice>" for choices. User commands use
+// "commands.user.<slug of the name>.name"
+func Localized(cmds []*discordgo.ApplicationCommand, bundle *i18n.Bundle) []*discordgo.ApplicationCommand {
+	var localizers []commandLocalizer
+	for _, tag := range bundle.LanguageTags() {
+		base, _ := tag.Base()
+		if loc, ok := discordLocales[base.String()]; ok {
+			localizers = append(localizers, commandLocalizer{
+				locale:    loc,
+				tag:       tag,
+				localizer: i18n.NewLocalizer(bundle, tag.String()),
+			})
+		}
+	}
+
+	localized := make([]*discordgo.ApplicationCommand, len(cmds))
+	for i, cmd := range cmds {
+		localized[i] = localizeCommand(cmd, localizers)
+	}
+	return localized
+}
+
+func localizeCommand(cmd *discordgo.ApplicationCommand, localizers []commandLocalizer) *discordgo.ApplicationCommand {
+	localized := *cmd
+	if commandType(cmd.Type) != discordgo.ChatApplicationCommand {
+		prefix := "commands.user." + strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(cmd.Name), "-"), "-")
+		localized.NameLocalizations = translationsPtr(translations(localizers, prefix+".name", validUserCommandName))
+		return &localized
+	}
+
+	prefix := "commands." + cmd.Name
+	localized.NameLocalizations = translationsPtr(translations(localizers, prefix+".name", validChatName))
+	localized.DescriptionLocalizations = translationsPtr(translations(localizers, prefix+".description", validDescription))
+	localized.Options = localizeOptions(cmd.Options, prefix, localizers)
+	return &localized
+}
+
+func localizeOptions(options []*discordgo.ApplicationCommandOption, prefix string, localizers []commandLocalizer) []*discordgo.ApplicationCommandOption {
+	if options == nil {
+		return nil
+	}
+	localized := make([]*discordgo.ApplicationCommandOption, len(options))
+	for i, opt := range options {
+		optPrefix := prefix + "." + opt.Name
+		localizedOpt := *opt
+		localizedOpt.NameLocalizations = translations(localizers, optPrefix+".name", validChatName)
+		localizedOpt.DescriptionLocalizations = translations(localizers, optPrefix+".description", validDescription)
+		localizedOpt.Options = localizeOptions(opt.Options, optPrefix, localizers)
+		if opt.Choices != nil {
+			localizedOpt.Choices = make([]*discordgo.ApplicationCommandOptionChoice, len(opt.Choices))
+			for j, choice := range opt.Choices {
+				localizedChoice := *choice
+				localizedChoice.NameLocalizations = translations(localizers, optPrefix+".choices."+choice.Name, validDescription)
+				localizedOpt.Choices[j] = &localizedChoice
+			}
+		}
+		localized[i] = &localizedOpt
+	}
+	return localized
+}
+
+// translations returns the message in every language that has its own translation for it, or nil if none do
+func translations(localizers []commandLocalizer, id string, valid func(string) bool) map[discordgo.Locale]string {
+	var localized map[discordgo.Locale]string
+	for _, l := range localizers {
+		msg, tag, err := l.localizer.LocalizeWithTag(&i18n.LocalizeConfig{MessageID: id})
+		// don't fall back to another language; Discord already shows the default
+		if err != nil || tag != l.tag || !valid(msg) {
+			continue
+		}
+		if localized == nil {
+			localized = make(map[discordgo.Locale]string)
+		}
+		localized[l.locale] = msg
+	}
+	return localized
+}
+
+func translationsPtr(localized map[discordgo.Locale]string) *map[discordgo.Locale]string {
+	if localized == nil {
+		return nil
+	}
+	return &localized
+}
+
+func validChatName(name string) bool {
+	return chatNameRegex.MatchString(name) && strings.ToLower(name) == name
+}
+
+func validUserCommandName(name string) bool {
+	return name != "" && len([]rune(name)) <= 32
+}
+
+func validDescription(description string) bool {
+	return description != "" && len([]rune(description)) <= 100
+}
+
+func localizationsEqual(a, b map[discordgo.Locale]string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
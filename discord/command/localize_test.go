@@ -0,0 +1,53 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+func TestLocalized(t *testing.T) {
+	bundle := i18n.NewBundle(language.English)
+	err := bundle.AddMessages(language.German,
+		&i18n.Message{ID: "commands.links.description", Other: "Namen verwalten"},
+		&i18n.Message{ID: "commands.links.name", Other: "verknüpfungen"},
+		&i18n.Message{ID: "commands.links.list.name", Other: "Ungültig Groß"},
+		&i18n.Message{ID: "commands.links.list.user.description", Other: "Benutzer"},
+		&i18n.Message{ID: "commands.user.link-to-color.name", Other: "Mit Farbe verknüpfen…"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Discord has no Afrikaans locale
+	err = bundle.AddMessages(language.Afrikaans, &i18n.Message{ID: "commands.links.description", Other: "Name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := Localized([]*discordgo.ApplicationCommand{&Links, &LinkUser}, bundle)
+	links, linkUser := cmds[0], cmds[1]
+	if links.DescriptionLocalizations == nil || len(*links.DescriptionLocalizations) != 1 ||
+		(*links.DescriptionLocalizations)[discordgo.German] != "Namen verwalten" {
+		t.Errorf("Expected only a German description, got %v", links.DescriptionLocalizations)
+	}
+	if links.NameLocalizations == nil || (*links.NameLocalizations)[discordgo.German] != "verknüpfungen" {
+		t.Errorf("Expected a German name, got %v", links.NameLocalizations)
+	}
+	if links.Options[0].NameLocalizations != nil {
+		t.Errorf("Expected invalid option names to be skipped, got %v", links.Options[0].NameLocalizations)
+	}
+	if links.Options[0].Options[0].DescriptionLocalizations[discordgo.German] != "Benutzer" {
+		t.Errorf("Expected a German nested option description, got %v", links.Options[0].Options[0].DescriptionLocalizations)
+	}
+	if links.Options[1].DescriptionLocalizations != nil {
+		t.Errorf("Expected untranslated options to have no localizations, got %v", links.Options[1].DescriptionLocalizations)
+	}
+	if linkUser.NameLocalizations == nil || (*linkUser.NameLocalizations)[discordgo.German] != "Mit Farbe verknüpfen…" {
+		t.Errorf("Expected a German user command name, got %v", linkUser.NameLocalizations)
+	}
+	if Links.DescriptionLocalizations != nil || Links.Options[0].Options[0].DescriptionLocalizations != nil {
+		t.Error("Expected the original commands to be left alone")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/automuteus/utils/pkg/game"
 	"github.com/bwmarrin/discordgo"
-	"os"
 )
 
 const (
@@ -38,11 +37,11 @@ func GetMapParams(options []*discordgo.ApplicationCommandInteractionDataOption)
 	return game.PlayMap(options[0].IntValue()), detailed
 }
 
-func MapResponse(mapType game.PlayMap, detailed bool) *discordgo.InteractionResponse {
+func MapResponse(baseURL string, mapType game.PlayMap, detailed bool) *discordgo.InteractionResponse {
 	return &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: FormMapUrl(os.Getenv("BASE_MAP_URL"), mapType, detailed),
+			Content: FormMapUrl(baseURL, mapType, detailed),
 		},
 	}
 }
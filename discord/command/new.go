@@ -1,7 +1,10 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
+	"time"
+
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -13,24 +16,53 @@ const (
 	NewSuccess NewStatus = iota
 	NewNoVoiceChannel
 	NewLockout
+	NewConnectCodeError
+	NewNoGame
+	NewMaintenance
 )
 
+const RegenerateLink = "regenerate-link"
+
 type NewInfo struct {
 	Hyperlink   string
 	MinimalURL  string
 	ConnectCode string
 	ActiveGames int64
+	// QRCode is a PNG encoding the Hyperlink; omitted from the response if empty
+	QRCode []byte
+	// MaintenanceETA is when maintenance is expected to be over; zero if that isn't known
+	MaintenanceETA time.Time
 }
 
+const captureQRCodeFilename = "capture.png"
+
 var New = discordgo.ApplicationCommand{
 	Name:        "new",
 	Description: "Start a new game",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        RegenerateLink,
+			Description: "Issue a new capture link for the current game, revoking the old one",
+			Required:    false,
+		},
+	},
+}
+
+func GetNewParams(options []*discordgo.ApplicationCommandInteractionDataOption) (regenerate bool) {
+	for _, option := range options {
+		if option.Name == RegenerateLink {
+			regenerate = option.BoolValue()
+		}
+	}
+	return regenerate
 }
 
 func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *discordgo.InteractionResponse {
 	var content string
 	var embeds []*discordgo.MessageEmbed
-	var flags uint64 = 1 << 6 // private message by default
+	var files []*discordgo.File
+	var flags discordgo.MessageFlags = 1 << 6 // private message by default
 
 	switch status {
 	case NewSuccess:
@@ -65,6 +97,18 @@ func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *
 				},
 			},
 		}
+		if len(info.QRCode) > 0 {
+			embeds[0].Image = &discordgo.MessageEmbedImage{
+				URL: "attachment://" + captureQRCodeFilename,
+			}
+			files = []*discordgo.File{
+				{
+					Name:        captureQRCodeFilename,
+					ContentType: "image/png",
+					Reader:      bytes.NewReader(info.QRCode),
+				},
+			}
+		}
 	case NewNoVoiceChannel:
 		content = sett.LocalizeMessage(&i18n.Message{
 			ID:    "commands.new.nochannel",
@@ -81,6 +125,18 @@ func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *
 		})
 		flags = 0 // public message
 
+	case NewConnectCodeError:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.new.connectcode.error",
+			Other: "I couldn't generate a unique connect code for your game. Please try again!",
+		})
+	case NewNoGame:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.new.regenerate.nogame",
+			Other: "There's no game running in this channel to regenerate the capture link for. Use `/new` to start one!",
+		})
+	case NewMaintenance:
+		content = MaintenanceMessage(info.MaintenanceETA, sett)
 	}
 	return &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -88,6 +144,27 @@ func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *
 			Flags:   flags,
 			Content: content,
 			Embeds:  embeds,
+			Files:   files,
 		},
 	}
 }
+
+// MaintenanceMessage explains that no new games can be started during maintenance, and when it should be over if eta
+// isn't zero
+func MaintenanceMessage(eta time.Time, sett *settings.GuildSettings) string {
+	if eta.IsZero() {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID: "commands.new.maintenance",
+			Other: "🛠️ AutoMuteUs is undergoing maintenance, so I can't start new games right now. " +
+				"Games that are already running aren't affected. Please try again later!",
+		})
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID: "commands.new.maintenance.eta",
+		Other: "🛠️ AutoMuteUs is undergoing maintenance, so I can't start new games right now. " +
+			"Games that are already running aren't affected. I should be back {{.ETA}}!",
+	}, map[string]interface{}{
+		// Discord shows this in the user's timezone, relative to now
+		"ETA": fmt.Sprintf("<t:%d:R>", eta.Unix()),
+	})
+}
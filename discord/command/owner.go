@@ -0,0 +1,354 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	OwnerSoftbans     = "softbans"
+	OwnerSoftbansList = "list"
+	OwnerSoftbansLift = "lift"
+	OwnerEndGame      = "end-game"
+	OwnerShards       = "shards"
+	OwnerMaintenance  = "maintenance"
+	OwnerAnnounce     = "announce"
+	OwnerDebugLogging = "debug-logging"
+)
+
+var minMaintenanceMinutes float64 = 1
+
+// Owner is only registered in the admin guild, and can only be used by the bot's owners
+var Owner = discordgo.ApplicationCommand{
+	Name:        "owner",
+	Description: "Tools for the owners of this AutoMuteUs instance",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        OwnerSoftbans,
+			Description: "Users that are softbanned for exceeding the rate limits",
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        OwnerSoftbansList,
+					Description: "List the softbanned users",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        OwnerSoftbansLift,
+					Description: "Lift a user's softban",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        User,
+							Description: "User to unban",
+							Type:        discordgo.ApplicationCommandOptionUser,
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        OwnerEndGame,
+			Description: "End any game",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "connect-code",
+					Description: "Connect code of the game",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        OwnerShards,
+			Description: "View the health of each shard",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        OwnerMaintenance,
+			Description: "Turn maintenance mode on or off",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "enabled",
+					Description: "Whether maintenance mode is on",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Required:    true,
+				},
+				{
+					Name:        "eta-minutes",
+					Description: "How many minutes maintenance should take",
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					MinValue:    &minMaintenanceMinutes,
+				},
+			},
+		},
+		{
+			Name:        OwnerAnnounce,
+			Description: "Post an announcement in every guild's announcement channel",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "message",
+					Description: "Announcement to post",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+					MaxLength:   2000,
+				},
+			},
+		},
+		{
+			Name:        OwnerDebugLogging,
+			Description: "Log everything about a guild, for troubleshooting it",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "guild-id",
+					Description: "ID of the guild",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        "enabled",
+					Description: "Whether debug logging is on for the guild",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// OwnerParams is what the owner command was used with; only the fields of the subcommand that was used are set
+type OwnerParams struct {
+	Action      string
+	UserID      string
+	GuildID     string
+	ConnectCode string
+	Enabled     bool
+	// ETA is when maintenance should be over; zero if it wasn't given
+	ETA     time.Time
+	Message string
+}
+
+func GetOwnerParams(s *discordgo.Session, options []*discordgo.ApplicationCommandInteractionDataOption) OwnerParams {
+	params := OwnerParams{Action: options[0].Name}
+	switch params.Action {
+	case OwnerSoftbans:
+		params.Action = OwnerSoftbans + " " + options[0].Options[0].Name
+		if params.Action == OwnerSoftbans+" "+OwnerSoftbansLift {
+			params.UserID = options[0].Options[0].Options[0].UserValue(s).ID
+		}
+	case OwnerEndGame:
+		params.ConnectCode = strings.ToUpper(strings.TrimSpace(options[0].Options[0].StringValue()))
+	case OwnerMaintenance, OwnerDebugLogging:
+		for _, opt := range options[0].Options {
+			switch opt.Name {
+			case "guild-id":
+				params.GuildID = strings.TrimSpace(opt.StringValue())
+			case "enabled":
+				params.Enabled = opt.BoolValue()
+			case "eta-minutes":
+				params.ETA = time.Now().Add(time.Duration(opt.IntValue()) * time.Minute)
+			}
+		}
+	case OwnerAnnounce:
+		params.Message = options[0].Options[0].StringValue()
+	}
+	return params
+}
+
+// ShardStatus is what each shard regularly reports about itself
+type ShardStatus struct {
+	ShardID     int   `json:"shardID"`
+	ShardCount  int   `json:"shardCount"`
+	Guilds      int   `json:"guilds"`
+	ActiveGames int   `json:"activeGames"`
+	LatencyMs   int64 `json:"latencyMs"`
+	Updated     int64 `json:"updated"`
+}
+
+func OwnerSoftbansResponse(banned map[string]time.Duration, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	var content string
+	switch {
+	case err != nil:
+		return PrivateErrorResponse(Owner.Name+" "+OwnerSoftbans, err, sett)
+	case len(banned) == 0:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.softbans.empty",
+			Other: "Nobody is softbanned right now",
+		})
+	default:
+		userIDs := make([]string, 0, len(banned))
+		for userID := range banned {
+			userIDs = append(userIDs, userID)
+		}
+		sort.Strings(userIDs)
+		buf := strings.Builder{}
+		for _, userID := range userIDs {
+			buf.WriteString(fmt.Sprintf("%s (`%s`): %s\n", discord.MentionByUserID(userID), userID, banned[userID].Round(time.Second)))
+		}
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.softbans.list",
+			Other: "Softbanned users, and how much longer they're banned for:\n{{.Users}}",
+		}, map[string]interface{}{
+			"Users": buf.String(),
+		})
+	}
+	return ownerResponse(content)
+}
+
+func OwnerLiftSoftbanResponse(userID string, wasBanned bool, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Owner.Name+" "+OwnerSoftbans, err, sett)
+	}
+	if !wasBanned {
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.softbans.notBanned",
+			Other: "{{.User}} wasn't softbanned, but I forgot their previous rate limit violations anyway",
+		}, map[string]interface{}{
+			"User": discord.MentionByUserID(userID),
+		}))
+	}
+	return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.owner.softbans.lifted",
+		Other: "{{.User}} is no longer softbanned",
+	}, map[string]interface{}{
+		"User": discord.MentionByUserID(userID),
+	}))
+}
+
+// OwnerEndGameResponse reports whether a shard running the game was found to end it. guildID is empty if the game
+// doesn't exist
+func OwnerEndGameResponse(connectCode, guildID string, shards int64, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	switch {
+	case err != nil:
+		return PrivateErrorResponse(Owner.Name+" "+OwnerEndGame, err, sett)
+	case guildID == "":
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.endGame.notFound",
+			Other: "I couldn't find a game with the connect code `{{.ConnectCode}}`",
+		}, map[string]interface{}{
+			"ConnectCode": connectCode,
+		}))
+	default:
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.endGame.success",
+			Other: "Asked {{.Shards}} shard(s) to end the game `{{.ConnectCode}}` in guild `{{.GuildID}}`; the one running it will end it",
+		}, map[string]interface{}{
+			"Shards":      shards,
+			"ConnectCode": connectCode,
+			"GuildID":     guildID,
+		}))
+	}
+}
+
+// OwnerShardsResponse lists the shards that reported their status; shards that haven't reported for longer than stale
+// are marked as down
+func OwnerShardsResponse(statuses []ShardStatus, stale time.Duration, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Owner.Name+" "+OwnerShards, err, sett)
+	}
+	if len(statuses) == 0 {
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.shards.empty",
+			Other: "No shards have reported their status",
+		}))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ShardID < statuses[j].ShardID
+	})
+	buf := strings.Builder{}
+	for _, status := range statuses {
+		age := time.Since(time.Unix(status.Updated, 0)).Round(time.Second)
+		health := "✅"
+		if age > stale {
+			health = "❌"
+		}
+		buf.WriteString(fmt.Sprintf("%s %d/%d: %d guilds, %d games, %dms, %s ago\n",
+			health, status.ShardID, status.ShardCount, status.Guilds, status.ActiveGames, status.LatencyMs, age))
+	}
+	return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.owner.shards.list",
+		Other: "Shards, with their guilds, active games, gateway latency, and when they last reported:\n```\n{{.Shards}}```",
+	}, map[string]interface{}{
+		"Shards": buf.String(),
+	}))
+}
+
+func OwnerMaintenanceResponse(enabled bool, eta time.Time, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Owner.Name+" "+OwnerMaintenance, err, sett)
+	}
+	if !enabled {
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.maintenance.disabled",
+			Other: "Maintenance mode is off",
+		}))
+	}
+	if eta.IsZero() {
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.maintenance.enabled",
+			Other: "Maintenance mode is on",
+		}))
+	}
+	return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.owner.maintenance.enabled.eta",
+		Other: "Maintenance mode is on until {{.ETA}}",
+	}, map[string]interface{}{
+		"ETA": fmt.Sprintf("<t:%d:t>", eta.Unix()),
+	}))
+}
+
+func OwnerAnnounceResponse(shards int64, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Owner.Name+" "+OwnerAnnounce, err, sett)
+	}
+	return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.owner.announce.success",
+		Other: "Sent the announcement to {{.Shards}} shard(s), which will post it in their guilds' announcement channels",
+	}, map[string]interface{}{
+		"Shards": shards,
+	}))
+}
+
+func OwnerDebugLoggingResponse(guildID string, enabled bool, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Owner.Name+" "+OwnerDebugLogging, err, sett)
+	}
+	if enabled {
+		return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.owner.debugLogging.enabled",
+			Other: "Everything about guild `{{.GuildID}}` is logged now; every shard will pick it up within a minute",
+		}, map[string]interface{}{
+			"GuildID": guildID,
+		}))
+	}
+	return ownerResponse(sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.owner.debugLogging.disabled",
+		Other: "Guild `{{.GuildID}}` is logged like every other guild again, unless it's configured otherwise",
+	}, map[string]interface{}{
+		"GuildID": guildID,
+	}))
+}
+
+func ownerResponse(content string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+			// don't ping the users that are listed
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetOwnerParams(t *testing.T) {
+	params := GetOwnerParams(nil, []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: OwnerSoftbans,
+			Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name: OwnerSoftbansLift,
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						{
+							Name:  User,
+							Type:  discordgo.ApplicationCommandOptionUser,
+							Value: "1234",
+						},
+					},
+				},
+			},
+		},
+	})
+	if params.Action != OwnerSoftbans+" "+OwnerSoftbansLift || params.UserID != "1234" {
+		t.Errorf("Unexpected params for lifting a softban: %+v", params)
+	}
+
+	params = GetOwnerParams(nil, []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: OwnerEndGame,
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name:  "connect-code",
+					Type:  discordgo.ApplicationCommandOptionString,
+					Value: " abcdefgh ",
+				},
+			},
+		},
+	})
+	if params.Action != OwnerEndGame || params.ConnectCode != "ABCDEFGH" {
+		t.Errorf("Unexpected params for ending a game: %+v", params)
+	}
+	params = GetOwnerParams(nil, []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: OwnerMaintenance,
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name:  "eta-minutes",
+					Type:  discordgo.ApplicationCommandOptionInteger,
+					Value: float64(30),
+				},
+				{
+					Name:  "enabled",
+					Type:  discordgo.ApplicationCommandOptionBoolean,
+					Value: true,
+				},
+			},
+		},
+	})
+	if !params.Enabled || time.Until(params.ETA).Round(time.Minute) != 30*time.Minute {
+		t.Errorf("Unexpected params for maintenance mode: %+v", params)
+	}
+
+	params = GetOwnerParams(nil, []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: OwnerDebugLogging,
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name:  "guild-id",
+					Type:  discordgo.ApplicationCommandOptionString,
+					Value: "141082723635691521 ",
+				},
+				{
+					Name:  "enabled",
+					Type:  discordgo.ApplicationCommandOptionBoolean,
+					Value: true,
+				},
+			},
+		},
+	})
+	if params.Action != OwnerDebugLogging || params.GuildID != "141082723635691521" || !params.Enabled {
+		t.Errorf("Unexpected params for debug logging: %+v", params)
+	}
+}
+
+func TestMaintenanceMessage(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	if strings.Contains(MaintenanceMessage(time.Time{}, sett), "<t:") {
+		t.Error("Expected no ETA when it isn't known")
+	}
+	if !strings.Contains(MaintenanceMessage(time.Unix(1700000000, 0), sett), "<t:1700000000:R>") {
+		t.Error("Expected the ETA as a relative Discord timestamp")
+	}
+}
+
+func TestOwnerShardsResponse(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	now := time.Now().Unix()
+	resp := OwnerShardsResponse([]ShardStatus{
+		{ShardID: 1, ShardCount: 2, Updated: now - 600},
+		{ShardID: 0, ShardCount: 2, Updated: now},
+	}, time.Minute, nil, sett)
+	content := resp.Data.Content
+	up, down := strings.Index(content, "✅ 0/2"), strings.Index(content, "❌ 1/2")
+	if up < 0 || down < 0 || up > down {
+		t.Errorf("Expected shard 0 to be up and listed before shard 1, which is down:\n%s", content)
+	}
+}
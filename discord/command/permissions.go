@@ -0,0 +1,146 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+type PermissionLevel int
+
+const (
+	PermissionEveryone PermissionLevel = iota
+	PermissionModerator
+	PermissionAdmin
+)
+
+// Permissions is the permission level needed to use each command or subcommand, keyed like PermissionKey; commands
+// that aren't listed can be used by everyone. Some subcommands need more when they affect other users, which their
+// descriptions mention
+var Permissions = map[string]PermissionLevel{
+	New.Name:                    PermissionModerator,
+	Pause.Name:                  PermissionModerator,
+	End.Name:                    PermissionModerator,
+	Link.Name:                   PermissionModerator,
+	Unlink.Name:                 PermissionModerator,
+	Settings.Name:               PermissionAdmin,
+	Links.Name + " " + LinksAdd: PermissionAdmin,
+	Stats.Name + " " + setting.Clear + " " + Guild: PermissionAdmin,
+}
+
+// userCommandPermissionKeys are the slash commands whose permissions also apply to the user commands that do the same
+var userCommandPermissionKeys = map[string]string{
+	LinkUser.Name:   Link.Name,
+	UnlinkUser.Name: Unlink.Name,
+}
+
+// PermissionKey identifies a command, and the subcommand group and subcommand that were used, like "stats clear guild"
+func PermissionKey(data discordgo.ApplicationCommandInteractionData) string {
+	if key, ok := userCommandPermissionKeys[data.Name]; ok {
+		return key
+	}
+	key := data.Name
+	options := data.Options
+	for len(options) > 0 && (options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup ||
+		options[0].Type == discordgo.ApplicationCommandOptionSubCommand) {
+		key += " " + options[0].Name
+		options = options[0].Options
+	}
+	return key
+}
+
+// PermissionKeys are the keys of all commands, subcommand groups and subcommands
+func PermissionKeys() []string {
+	var keys []string
+	var add func(key string, options []*discordgo.ApplicationCommandOption)
+	add = func(key string, options []*discordgo.ApplicationCommandOption) {
+		keys = append(keys, key)
+		for _, opt := range options {
+			if opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup ||
+				opt.Type == discordgo.ApplicationCommandOptionSubCommand {
+				add(key+" "+opt.Name, opt.Options)
+			}
+		}
+	}
+	for _, cmd := range All {
+		add(cmd.Name, cmd.Options)
+	}
+	return keys
+}
+
+// PermissionKeyParents are the key and the keys of the commands and subcommand groups it belongs to, most specific
+// first; settings for a command apply to all its subcommands unless they have their own
+func PermissionKeyParents(key string) []string {
+	keys := []string{key}
+	for i := strings.LastIndex(key, " "); i > 0; i = strings.LastIndex(key, " ") {
+		key = key[:i]
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DefaultPermission is the permission level a command or subcommand needs when the guild hasn't chosen who may use it
+func DefaultPermission(key string) PermissionLevel {
+	for _, k := range PermissionKeyParents(key) {
+		if level, ok := Permissions[k]; ok {
+			return level
+		}
+	}
+	return PermissionEveryone
+}
+
+// PermissionKeyChoices are the permission keys that contain typed, for autocompletion
+func PermissionKeyChoices(typed string) []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, key := range PermissionKeys() {
+		if strings.Contains(key, typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  key,
+				Value: key,
+			})
+		}
+	}
+	return choices
+}
+
+// EffectivePermission describes who may use the command or subcommand with the permission key in the guild: the
+// roles and users it allowed with /settings permissions, or the default permission level. ext can be nil outside guilds
+func EffectivePermission(key string, ext *storage.ExtendedGuildSettings, sett *settings.GuildSettings) string {
+	if ext != nil {
+		for _, k := range PermissionKeyParents(key) {
+			if perm, ok := ext.CommandPermissions[k]; ok {
+				return sett.LocalizeMessage(&i18n.Message{
+					ID:    "commands.permissions.custom",
+					Other: "Admins and {{.Mentions}}",
+				}, map[string]interface{}{
+					"Mentions": strings.Join(perm.Mentions(), ", "),
+				})
+			}
+		}
+	}
+	return DefaultPermission(key).Localize(sett)
+}
+
+func (level PermissionLevel) Localize(sett *settings.GuildSettings) string {
+	switch level {
+	case PermissionModerator:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.permissions.moderator",
+			Other: "Moderators (the bot's permission roles) and admins",
+		})
+	case PermissionAdmin:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.permissions.admin",
+			Other: "Admins only",
+		})
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.permissions.everyone",
+			Other: "Everyone",
+		})
+	}
+}
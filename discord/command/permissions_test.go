@@ -0,0 +1,87 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPermissionKey(t *testing.T) {
+	data := discordgo.ApplicationCommandInteractionData{
+		Name: Stats.Name,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{
+				Name: setting.Clear,
+				Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name: User,
+						Type: discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandInteractionDataOption{
+							{
+								Name:  User,
+								Type:  discordgo.ApplicationCommandOptionUser,
+								Value: "1234",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if key := PermissionKey(data); key != "stats clear user" {
+		t.Errorf("Expected stats clear user, got %s", key)
+	}
+	if key := PermissionKey(discordgo.ApplicationCommandInteractionData{Name: LinkUser.Name}); key != Link.Name {
+		t.Errorf("Expected the user command to share the permissions of /link, got %s", key)
+	}
+
+	keys := PermissionKeys()
+	for _, key := range []string{"new", "stats clear user", "settings permissions role"} {
+		if !contains(keys, key) {
+			t.Errorf("Expected %s to be a permission key", key)
+		}
+	}
+}
+
+func TestDefaultPermission(t *testing.T) {
+	parents := PermissionKeyParents("stats clear guild")
+	if len(parents) != 3 || parents[1] != "stats clear" || parents[2] != "stats" {
+		t.Errorf("Unexpected parents: %v", parents)
+	}
+	if DefaultPermission("stats clear guild") != PermissionAdmin || DefaultPermission("stats clear user") != PermissionEveryone {
+		t.Error("Expected only clearing the guild's stats to need admin")
+	}
+	if DefaultPermission("settings language") != PermissionAdmin {
+		t.Error("Expected settings subcommands to need admin like /settings")
+	}
+}
+
+func TestEffectivePermission(t *testing.T) {
+	sett := settings.MakeGuildSettings()
+	if perm := EffectivePermission("stats clear guild", nil, sett); perm != PermissionAdmin.Localize(sett) {
+		t.Errorf("Expected the default permission without extended settings, got %s", perm)
+	}
+	ext := storage.MakeExtendedGuildSettings()
+	ext.CommandPermissions = map[string]*storage.CommandPermission{
+		"stats": {RoleIDs: []string{"1"}, UserIDs: []string{"2"}},
+	}
+	if perm := EffectivePermission("stats clear guild", ext, sett); perm != "Admins and <@&1>, <@!2>" {
+		t.Errorf("Expected the permission set on /stats, got %s", perm)
+	}
+	if perm := EffectivePermission("new", ext, sett); perm != DefaultPermission("new").Localize(sett) {
+		t.Errorf("Expected the default permission for other commands, got %s", perm)
+	}
+}
+
+func contains(arr []string, elem string) bool {
+	for _, v := range arr {
+		if v == elem {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	PersonalLinkShow       = "show"
+	PersonalLinkRegenerate = "regenerate"
+	PersonalLinkRevoke     = "revoke"
+)
+
+var PersonalLink = discordgo.ApplicationCommand{
+	Name:        "personal-link",
+	Description: "Manage your personal capture link, reused for every game you host",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        PersonalLinkShow,
+			Description: "Show your personal capture link, creating it if you don't have one",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        PersonalLinkRegenerate,
+			Description: "Replace your personal capture link, revoking the old one",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        PersonalLinkRevoke,
+			Description: "Revoke your personal capture link; new games will use a one-time link",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+	},
+}
+
+func GetPersonalLinkParams(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	return options[0].Name
+}
+
+func PersonalLinkResponse(action string, info NewInfo, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(PersonalLink.Name, err, sett)
+	}
+	var content string
+	switch action {
+	case PersonalLinkShow:
+		fallthrough
+	case PersonalLinkRegenerate:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID: "commands.personal-link.success",
+			Other: "Your personal capture link is:\n <{{.hyperlink}}>\n\n" +
+				"Link your capture once, and it will reconnect to every game you start with `/new`. " +
+				"Keep it secret; anyone with this link can control the game you're hosting!\n" +
+				"URL: `{{.url}}` Code: `{{.code}}`",
+		}, map[string]interface{}{
+			"hyperlink": info.Hyperlink,
+			"url":       info.MinimalURL,
+			"code":      info.ConnectCode,
+		})
+	case PersonalLinkRevoke:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.personal-link.revoke",
+			Other: "Your personal capture link has been revoked. New games you start will use a one-time link",
+		})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+		},
+	}
+}
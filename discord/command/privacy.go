@@ -3,8 +3,12 @@ package command
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/settings"
-	"github.com/automuteus/utils/pkg/storage"
+	storageutils "github.com/automuteus/utils/pkg/storage"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
@@ -55,7 +59,9 @@ func GetPrivacyParam(options []*discordgo.ApplicationCommandInteractionDataOptio
 	return options[0].StringValue()
 }
 
-func PrivacyResponse(status string, cached map[string]interface{}, user *storage.PostgresUser, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+// PrivacyResponse shows the data stored for the user: the cached in-game names, whether they're opted in, and the
+// profile and personal connect code that are kept across servers
+func PrivacyResponse(status string, cached map[string]interface{}, user *storageutils.PostgresUser, profile *storage.UserProfile, personalCode string, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
 	var content string
 	switch status {
 	case PrivacyInfo:
@@ -96,6 +102,30 @@ func PrivacyResponse(status string, cached map[string]interface{}, user *storage
 				})
 			}
 		}
+		if profile != nil {
+			color := sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.profile.nocolor",
+				Other: "none",
+			})
+			if profile.PreferredColor != storage.NoPreferredColor {
+				color = game.GetColorStringForInt(profile.PreferredColor)
+			}
+			content += "\n" + sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.privacy.showme.profile",
+				Other: "❗ Your profile has the in-game names `{{.Names}}` and the preferred color `{{.Color}}`",
+			}, map[string]interface{}{
+				"Names": strings.Join(profile.InGameNames, "`, `"),
+				"Color": color,
+			})
+		}
+		if personalCode != "" {
+			content += "\n" + sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.privacy.showme.personalcode",
+				Other: "❗ Your personal connect code is `{{.Code}}`",
+			}, map[string]interface{}{
+				"Code": personalCode,
+			})
+		}
 
 	case PrivacyOptOut:
 		fallthrough
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	ProfileSet   = "set"
+	ProfileShow  = "show"
+	ProfileClear = "clear"
+
+	// MaxProfileNames is how many in-game names a profile can hold
+	MaxProfileNames = 5
+)
+
+type ProfileStatus int
+
+const (
+	ProfileSuccess ProfileStatus = iota
+	ProfileNotFound
+	ProfileInvalidNames
+)
+
+var Profile = discordgo.ApplicationCommand{
+	Name:        "profile",
+	Description: "Register the in-game names and color you play with, to be linked automatically in any server",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        ProfileSet,
+			Description: "Set the in-game names and color you usually play with",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "names",
+					Description: "Your in-game names, separated by commas",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        "color",
+					Description: "The color you usually play as",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+					Choices:     colorsToCommandChoices(),
+				},
+			},
+		},
+		{
+			Name:        ProfileShow,
+			Description: "Show your profile",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        ProfileClear,
+			Description: "Delete your profile",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+	},
+}
+
+// GetProfileParams returns the subcommand, and for ProfileSet the names (nil if they aren't valid) and color
+func GetProfileParams(options []*discordgo.ApplicationCommandInteractionDataOption) (action string, names []string, color string) {
+	action = options[0].Name
+	for _, opt := range options[0].Options {
+		switch opt.Name {
+		case "names":
+			names = ParseProfileNames(opt.StringValue())
+		case "color":
+			color = opt.StringValue()
+		}
+	}
+	return action, names, color
+}
+
+// ParseProfileNames splits a comma-separated list of in-game names, returning nil if any is empty or too long for
+// Among Us, or if there are too many
+func ParseProfileNames(arg string) []string {
+	var names []string
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || len([]rune(name)) > amongus.MaxInGameNameLength {
+			return nil
+		}
+		names = append(names, name)
+	}
+	if len(names) > MaxProfileNames {
+		return nil
+	}
+	return names
+}
+
+func ProfileResponse(status ProfileStatus, action string, names []string, color string, err error, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if err != nil {
+		return PrivateErrorResponse(Profile.Name, err, sett)
+	}
+	if color == "" {
+		color = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.profile.nocolor",
+			Other: "none",
+		})
+	}
+	var content string
+	switch status {
+	case ProfileNotFound:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.profile.notfound",
+			Other: "You don't have a profile yet. Create one with `/profile set`",
+		})
+	case ProfileInvalidNames:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.profile.invalidnames",
+			Other: "Please provide up to {{.Max}} in-game names, separated by commas, of no more than {{.Length}} characters each",
+		}, map[string]interface{}{
+			"Max":    MaxProfileNames,
+			"Length": amongus.MaxInGameNameLength,
+		})
+	case ProfileSuccess:
+		switch action {
+		case ProfileSet:
+			fallthrough
+		case ProfileShow:
+			content = sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.profile.show",
+				Other: "In-game names: `{{.Names}}`\nPreferred color: `{{.Color}}`\n\nI'll link you to these names in any server you play in",
+			}, map[string]interface{}{
+				"Names": strings.Join(names, "`, `"),
+				"Color": color,
+			})
+		case ProfileClear:
+			content = sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.profile.clear",
+				Other: "Your profile has been deleted",
+			})
+		}
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6,
+			Content: content,
+		},
+	}
+}
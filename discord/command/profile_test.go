@@ -0,0 +1,21 @@
+package command
+
+import (
+	"testing"
+)
+
+func TestParseProfileNames(t *testing.T) {
+	names := ParseProfileNames(" Bob, Zoë ,xX_Sn1per ")
+	if len(names) != 3 || names[0] != "Bob" || names[1] != "Zoë" || names[2] != "xX_Sn1per" {
+		t.Errorf("Expected names to be split and trimmed, got %v", names)
+	}
+	if ParseProfileNames("Bob,,Alice") != nil {
+		t.Error("Expected empty names to be rejected")
+	}
+	if ParseProfileNames("Christopher") != nil {
+		t.Error("Expected names longer than the in-game limit to be rejected")
+	}
+	if ParseProfileNames("a,b,c,d,e,f") != nil {
+		t.Error("Expected too many names to be rejected")
+	}
+}
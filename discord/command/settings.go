@@ -29,6 +29,21 @@ func GetSettingsParams(options []*discordgo.ApplicationCommandInteractionDataOpt
 			return sett.Name, args
 		}
 		// convert the value we received into the format we'd expect
+		// in this case, a subcommand with several options: its name, then the options in the order they're defined
+		if arg.Type == discordgo.ApplicationCommandOptionSubCommand && len(arg.Options) > 1 {
+			args = []string{v.Name}
+			for _, subArg := range arg.Options {
+				value := ""
+				for _, subOpt := range v.Options {
+					if subOpt.Name == subArg.Name {
+						value = setting.ToString(subOpt)
+						break
+					}
+				}
+				args = append(args, value)
+			}
+			return sett.Name, args
+		}
 		// in this case, a subcommand that has options of its own
 		if arg.Type == discordgo.ApplicationCommandOptionSubCommand && len(v.Options) > 0 {
 			args[i] = setting.ToString(v.Options[0])
@@ -56,3 +56,37 @@ func TestGetSettingsParams(t *testing.T) {
 }
 
 // TODO construct a test to validate complex settings behavior, like voice rules or delays
+
+func TestGetSettingsParamsSeveralOptions(t *testing.T) {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: setting.CommandPermissions,
+			Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Name: setting.Role,
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						{
+							Name:  setting.Role,
+							Type:  discordgo.ApplicationCommandOptionRole,
+							Value: "1234",
+						},
+						{
+							Name:  setting.Command,
+							Type:  discordgo.ApplicationCommandOptionString,
+							Value: "stats clear",
+						},
+					},
+				},
+			},
+		},
+	}
+	settingName, args := GetSettingsParams(options)
+	if settingName != setting.CommandPermissions {
+		t.Errorf("Expected the permissions setting, got %s", settingName)
+	}
+	if len(args) != 3 || args[0] != setting.Role || args[1] != "stats clear" || args[2] != "<@&1234>" {
+		t.Errorf("Expected the subcommand followed by its options in order, got %v", args)
+	}
+}
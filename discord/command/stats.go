@@ -8,6 +8,7 @@ import (
 const (
 	Match = "match"
 	Guild = "guild"
+	Me    = "me"
 )
 
 var Stats = discordgo.ApplicationCommand{
@@ -38,10 +39,11 @@ var Stats = discordgo.ApplicationCommand{
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 					Options: []*discordgo.ApplicationCommandOption{
 						{
-							Name:        Match,
-							Description: "Match ID whose stats you want to view",
-							Type:        discordgo.ApplicationCommandOptionString,
-							Required:    true,
+							Name:         Match,
+							Description:  "Match ID whose stats you want to view",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
 						},
 					},
 				},
@@ -50,6 +52,11 @@ var Stats = discordgo.ApplicationCommand{
 					Description: "View this guild's stats",
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 				},
+				{
+					Name:        Me,
+					Description: "View your stats across every server (also works in DMs)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
 			},
 		},
 		{
@@ -80,6 +87,8 @@ var Stats = discordgo.ApplicationCommand{
 	},
 }
 
+// GetStatsParams returns the action, what it's for, and its ID. The ID is empty for Me, which is always the user
+// running the command
 func GetStatsParams(s *discordgo.Session, guildID string, options []*discordgo.ApplicationCommandInteractionDataOption) (action string, opType string, id string) {
 	action = options[0].Name
 	opType = options[0].Options[0].Name
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automuteus/utils/pkg/locale"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Registered is every command the bot registers with Discord: the slash commands, then the user context-menu commands
+func Registered() []*discordgo.ApplicationCommand {
+	cmds := make([]*discordgo.ApplicationCommand, 0, len(All)+len(UserCommands))
+	cmds = append(cmds, All...)
+	return append(cmds, UserCommands...)
+}
+
+// SyncReport is how the commands registered with Discord differ from the ones the bot has, by name
+type SyncReport struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+func (report SyncReport) InSync() bool {
+	return len(report.Added) == 0 && len(report.Removed) == 0 && len(report.Changed) == 0
+}
+
+func (report SyncReport) String() string {
+	if report.InSync() {
+		return "commands are up to date"
+	}
+	var parts []string
+	if len(report.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(report.Added, ", "))
+	}
+	if len(report.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(report.Removed, ", "))
+	}
+	if len(report.Changed) > 0 {
+		parts = append(parts, "changed: "+strings.Join(report.Changed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Diff compares the commands registered with Discord to the desired ones, including all their options
+func Diff(registered, desired []*discordgo.ApplicationCommand) SyncReport {
+	existing := make(map[string]*discordgo.ApplicationCommand, len(registered))
+	for _, cmd := range registered {
+		existing[commandKey(cmd)] = cmd
+	}
+
+	var report SyncReport
+	for _, cmd := range desired {
+		key := commandKey(cmd)
+		if old, ok := existing[key]; !ok {
+			report.Added = append(report.Added, cmd.Name)
+		} else if !commandsEqual(old, cmd) {
+			report.Changed = append(report.Changed, cmd.Name)
+		}
+		delete(existing, key)
+	}
+	for _, cmd := range existing {
+		report.Removed = append(report.Removed, cmd.Name)
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Strings(report.Changed)
+	return report
+}
+
+// Sync overwrites the commands registered with Discord (globally if guildID is empty) with cmds, localized from the
+// locale bundle, but only if they differ. If dryRun is true, it only reports what would change
+func Sync(s *discordgo.Session, appID, guildID string, cmds []*discordgo.ApplicationCommand, dryRun bool) (SyncReport, error) {
+	registered, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	desired := Localized(cmds, locale.GetBundle())
+	report := Diff(registered, desired)
+	if report.InSync() || dryRun {
+		return report, nil
+	}
+	_, err = s.ApplicationCommandBulkOverwrite(appID, guildID, desired)
+	return report, err
+}
+
+// commands of different types (e.g. /unlink and the Unlink context-menu command) can share a name
+func commandKey(cmd *discordgo.ApplicationCommand) string {
+	return fmt.Sprintf("%d:%s", commandType(cmd.Type), cmd.Name)
+}
+
+// Discord fills in the chat command type when it's left out
+func commandType(t discordgo.ApplicationCommandType) discordgo.ApplicationCommandType {
+	if t == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return t
+}
+
+func commandsEqual(a, b *discordgo.ApplicationCommand) bool {
+	return a.Name == b.Name &&
+		a.Description == b.Description &&
+		commandType(a.Type) == commandType(b.Type) &&
+		localizationsEqual(derefLocalizations(a.NameLocalizations), derefLocalizations(b.NameLocalizations)) &&
+		localizationsEqual(derefLocalizations(a.DescriptionLocalizations), derefLocalizations(b.DescriptionLocalizations)) &&
+		optionsEqual(a.Options, b.Options)
+}
+
+func optionsEqual(a, b []*discordgo.ApplicationCommandOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name ||
+			a[i].Description != b[i].Description ||
+			a[i].Type != b[i].Type ||
+			a[i].Required != b[i].Required ||
+			a[i].Autocomplete != b[i].Autocomplete ||
+			!localizationsEqual(a[i].NameLocalizations, b[i].NameLocalizations) ||
+			!localizationsEqual(a[i].DescriptionLocalizations, b[i].DescriptionLocalizations) ||
+			!choicesEqual(a[i].Choices, b[i].Choices) ||
+			!optionsEqual(a[i].Options, b[i].Options) {
+			return false
+		}
+	}
+	return true
+}
+
+// some choices are built from maps, so their order isn't stable; Discord gives numbers back as floats
+func choicesEqual(a, b []*discordgo.ApplicationCommandOptionChoice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	choices := make(map[string]int, len(a))
+	for _, c := range a {
+		choices[fmt.Sprintf("%s=%v%v", c.Name, c.Value, c.NameLocalizations)]++
+	}
+	for _, c := range b {
+		key := fmt.Sprintf("%s=%v%v", c.Name, c.Value, c.NameLocalizations)
+		if choices[key] == 0 {
+			return false
+		}
+		choices[key]--
+	}
+	return true
+}
+
+func derefLocalizations(localized *map[discordgo.Locale]string) map[discordgo.Locale]string {
+	if localized == nil {
+		return nil
+	}
+	return *localized
+}
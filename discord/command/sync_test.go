@@ -0,0 +1,74 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// registeredCopy round-trips the commands through JSON, like they come back from Discord
+func registeredCopy(t *testing.T, cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	data, err := json.Marshal(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registered []*discordgo.ApplicationCommand
+	if err := json.Unmarshal(data, &registered); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range registered {
+		if cmd.Type == 0 {
+			cmd.Type = discordgo.ChatApplicationCommand
+		}
+	}
+	return registered
+}
+
+func TestDiffInSync(t *testing.T) {
+	desired := Registered()
+	registered := registeredCopy(t, desired)
+	// Discord doesn't keep the order of choices built from maps
+	for _, cmd := range registered {
+		for _, opt := range cmd.Options {
+			for i, j := 0, len(opt.Choices)-1; i < j; i, j = i+1, j-1 {
+				opt.Choices[i], opt.Choices[j] = opt.Choices[j], opt.Choices[i]
+			}
+		}
+	}
+	if report := Diff(registered, desired); !report.InSync() {
+		t.Errorf("Expected registered commands to be in sync, got %s", report)
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	registered := registeredCopy(t, Registered())
+	registered = append(registered, &discordgo.ApplicationCommand{Name: "old", Type: discordgo.ChatApplicationCommand})
+	for _, cmd := range registered {
+		if cmd.Name == Link.Name {
+			cmd.Options[0].Description = "outdated"
+		}
+	}
+	registered = removeCommand(registered, Help.Name)
+
+	report := Diff(registered, Registered())
+	if len(report.Added) != 1 || report.Added[0] != Help.Name {
+		t.Errorf("Expected %s to be added, got %v", Help.Name, report.Added)
+	}
+	if len(report.Removed) != 1 || report.Removed[0] != "old" {
+		t.Errorf("Expected old to be removed, got %v", report.Removed)
+	}
+	if len(report.Changed) != 1 || report.Changed[0] != Link.Name {
+		t.Errorf("Expected %s to be changed, got %v", Link.Name, report.Changed)
+	}
+}
+
+func removeCommand(cmds []*discordgo.ApplicationCommand, name string) []*discordgo.ApplicationCommand {
+	kept := make([]*discordgo.ApplicationCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		if cmd.Name != name {
+			kept = append(kept, cmd)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,61 @@
+package command
+
+import (
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// LinkUser and UnlinkUser are in the menu when right-clicking a user, as a quicker alternative to /link and /unlink
+var LinkUser = discordgo.ApplicationCommand{
+	Name: "Link to color…",
+	Type: discordgo.UserApplicationCommand,
+}
+
+var UnlinkUser = discordgo.ApplicationCommand{
+	Name: "Unlink",
+	Type: discordgo.UserApplicationCommand,
+}
+
+// UserCommands is all user context-menu commands for the bot. They're registered alongside All
+var UserCommands = []*discordgo.ApplicationCommand{
+	&LinkUser,
+	&UnlinkUser,
+}
+
+// LinkUserResponse asks which of the colors in the current game the user should be linked to
+func LinkUserResponse(userID, customID string, options []discordgo.SelectMenuOption, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if len(options) == 0 {
+		return PrivateResponse(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.link-user.noplayers",
+			Other: "No players have been detected in the current game yet",
+		}))
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: 1 << 6,
+			Content: sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.link-user.select",
+				Other: "Which color should {{.UserMention}} be linked to?",
+			}, map[string]interface{}{
+				"UserMention": discord.MentionByUserID(userID),
+			}),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID: customID,
+							Placeholder: sett.LocalizeMessage(&i18n.Message{
+								ID:    "commands.link-user.placeholder",
+								Other: "Select a color",
+							}),
+							Options: options,
+						},
+					},
+				},
+			},
+		},
+	}
+}
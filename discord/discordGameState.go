@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/automuteus/automuteus/amongus"
 	"github.com/automuteus/utils/pkg/settings"
@@ -31,6 +32,26 @@ type GameState struct {
 	GameData amongus.GameData `json:"amongUsData"`
 }
 
+// UnmarshalJSON links users saved before links included the color to the only player with their name, if there is one
+func (dgs *GameState) UnmarshalJSON(data []byte) error {
+	type gameState GameState
+	if err := json.Unmarshal(data, (*gameState)(dgs)); err != nil {
+		return err
+	}
+	for userID, user := range dgs.UserData {
+		if user.InGameName == amongus.UnlinkedPlayerName || user.PlayerColor != amongus.UnlinkedPlayer.Color {
+			continue
+		}
+		if playerData, found := dgs.GameData.GetByName(user.InGameName); found {
+			user.Link(playerData)
+		} else {
+			user.Unlink()
+		}
+		dgs.UserData[userID] = user
+	}
+	return nil
+}
+
 func NewDiscordGameState(guildID string) *GameState {
 	dgs := GameState{GuildID: guildID}
 	dgs.Reset()
@@ -82,7 +103,7 @@ func (dgs *GameState) ToEmojiEmbedFields(emojis AlivenessEmojis, sett *settings.
 			break
 		}
 		for _, userData := range dgs.UserData {
-			if userData.InGameName == player.Name {
+			if userData.IsLinkedTo(player) {
 				emoji := emojis[player.IsAlive][player.Color]
 				unsorted[player.Color] = &discordgo.MessageEmbedField{
 					Name:   player.Name,
@@ -0,0 +1,64 @@
+package discord
+
+import (
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// dmCommandHandler handles interactions in DMs. Only the commands that don't depend on a guild work there; the rest
+// are refused
+func (bot *Bot) dmCommandHandler(s *discordgo.Session, i *discordgo.InteractionCreate, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if i.User == nil || redis_common.IsUserBanned(bot.RedisInterface.client, i.User.ID) {
+		return nil
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		// everything we autocomplete belongs to a guild
+		return command.AutocompleteResponse(nil)
+	case discordgo.InteractionMessageComponent:
+		if command.IsHelpPageCustomID(i.MessageComponentData().CustomID) {
+			return command.HelpPageResponse(i.MessageComponentData().CustomID, sett, nil)
+		}
+		return command.DmResponse(sett)
+	case discordgo.InteractionApplicationCommand:
+	default:
+		return command.DmResponse(sett)
+	}
+
+	name := i.ApplicationCommandData().Name
+	if result := bot.rateLimit(i.User.ID, "", name); !result.Allowed {
+		return bot.rateLimitResponse(result, sett)
+	}
+
+	options := i.ApplicationCommandData().Options
+	switch name {
+	case command.Help.Name:
+		return command.HelpResponse(sett, nil, options)
+
+	case command.Info.Name:
+		return command.InfoResponse(bot.getInfo(), "", sett)
+
+	case command.Privacy.Name:
+		return bot.privacyResponse("", i.User.ID, command.GetPrivacyParam(options), sett)
+
+	case command.Stats.Name:
+		// only /stats view me is about the user alone
+		if options[0].Name == setting.View && options[0].Options[0].Name == command.Me {
+			return &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{
+						bot.GlobalUserStatsEmbed(i.User, sett),
+					},
+					// it's a DM, but don't ping the user anyway
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			}
+		}
+	}
+	return command.DmResponse(sett)
+}
@@ -3,9 +3,9 @@ package discord
 import (
 	"encoding/base64"
 	"io/ioutil"
-	"log"
 	"net/http"
 
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/utils/pkg/game"
 
 	"github.com/bwmarrin/discordgo"
@@ -15,7 +15,6 @@ const (
 	UnlinkEmojiName = "auunlink"
 	X               = "❌"
 	ThumbsUp        = "👍"
-	Hourglass       = "⌛"
 )
 
 // Emoji struct for discord
@@ -39,12 +38,12 @@ func (e *Emoji) DownloadAndBase64Encode() string {
 	url := e.GetDiscordCDNUrl()
 	response, err := http.Get(url)
 	if err != nil {
-		log.Println(err)
+		logging.Default().Error("Couldn't download the emoji", "emoji", e.Name, "err", err)
 	}
 	defer response.Body.Close()
 	bytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		log.Println(err)
+		logging.Default().Error("Couldn't read the emoji", "emoji", e.Name, "err", err)
 	}
 	encodedStr := base64.StdEncoding.EncodeToString(bytes)
 	return "data:image/png;base64," + encodedStr
@@ -70,11 +69,11 @@ func (bot *Bot) addAllMissingEmojis(s *discordgo.Session, guildID string, alive
 		}
 		if !alreadyExists {
 			b64 := emoji.DownloadAndBase64Encode()
-			em, err := s.GuildEmojiCreate(guildID, emoji.Name, b64, nil)
+			em, err := s.GuildEmojiCreate(guildID, &discordgo.EmojiParams{Name: emoji.Name, Image: b64})
 			if err != nil {
-				log.Println(err)
+				guildLogger(guildID).Error("Couldn't add the emoji", "emoji", emoji.Name, "err", err)
 			} else {
-				log.Printf("Added emoji %s successfully!\n", emoji.Name)
+				guildLogger(guildID).Info("Added the emoji", "emoji", emoji.Name)
 				emoji.ID = em.ID
 				bot.StatusEmojis[alive][i] = emoji
 			}
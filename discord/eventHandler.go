@@ -6,55 +6,101 @@ import (
 	"errors"
 	"fmt"
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/capture"
+	jobs "github.com/automuteus/automuteus/job"
 	"github.com/automuteus/automuteus/metrics"
 	"github.com/automuteus/utils/pkg/discord"
 	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/automuteus/utils/pkg/storage"
 	"github.com/automuteus/utils/pkg/task"
-	"github.com/go-redis/redis/v8"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// EndGameMessage ends the game if it's true. If it's false, the subscription just stops, because a new game in the same
+// channel took over the connect code
 type EndGameMessage bool
 
 func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGameChannel chan EndGameMessage) {
-	log.Println("Started Redis Subscription worker for " + connectCode)
+	logger := gameLogger(guildID, connectCode)
+	logger.Info("Started the capture event subscription")
 
-	notify := task.Subscribe(ctx, bot.RedisInterface.client, connectCode)
+	notify := bot.JobSource.Subscribe(connectCode)
 
 	timer := time.NewTimer(time.Second * time.Duration(bot.captureTimeout))
 
+	// jobs can be redelivered or lost if the capture reconnects; only this worker pops jobs for the connect code
+	tracker := jobs.Tracker{}
+	// with signed links, only the capture that connected with the current link can drive the game. If we're
+	// resubscribing to a game in progress, the capture may have connected already
+	session := bot.RedisInterface.GetCaptureSession(connectCode)
+	// personal links aren't signed; they're revoked with /personal-link instead
+	verifyLinks := bot.captureSigner != nil && !bot.isPersonalConnectCode(connectCode)
+
 	dgsRequest := GameStateRequest{
 		GuildID:     guildID,
 		ConnectCode: connectCode,
 	}
 
 	// indicate to the broker that we're online and ready to start processing messages
-	task.Ack(ctx, bot.RedisInterface.client, connectCode)
+	bot.JobSource.Ack(connectCode)
 
 	for {
 		select {
-		case message := <-notify.Channel():
+		case _, ok := <-notify.Notify():
 			timer.Reset(time.Second * time.Duration(bot.captureTimeout))
-			if message == nil {
+			if !ok {
 				break
 			}
 
 			// anytime we get a notification message, continue pulling messages off the list until there are no more
 			for {
-				job, err := task.PopJob(ctx, bot.RedisInterface.client, connectCode)
-				if errors.Is(err, redis.Nil) {
+				job, err := bot.JobSource.Pop(connectCode)
+				if errors.Is(err, jobs.ErrNoJobs) {
 					break
+				} else if errors.Is(err, jobs.ErrMalformed) {
+					logger.Warn("Dropped a malformed capture event", "err", err)
+					bot.RedisInterface.RecordJob(connectCode, jobs.NewRecord(job, jobs.StatusFailed, err))
+					continue
 				} else if err != nil {
-					log.Println(err)
+					logger.Error("Couldn't pop a capture event", "err", err)
 					break
 				}
-				log.Printf("Popped job of type %d w/ payload %s\n", job.JobType, job.Payload.(string))
+				if verifyLinks {
+					var err error
+					if job.JobType == task.ConnectionJob {
+						session, err = bot.connectCaptureSession(connectCode, session, job)
+					} else if !session.Allows(job.Session) {
+						err = capture.ErrForeignSession
+					} else if bot.captureLinkRevoked(connectCode, session.Nonce) {
+						err = capture.ErrRevokedToken
+					}
+					if err != nil {
+						if errors.Is(err, capture.ErrNoSession) {
+							logger.Error("Refused a capture event without a session; signed capture links need a broker that tags events with them", "type", job.JobType)
+						} else {
+							logger.Warn("Refused a capture event without a valid capture link", "type", job.JobType, "err", err)
+						}
+						bot.RedisInterface.RecordJob(connectCode, jobs.NewRecord(job, jobs.StatusFailed, err))
+						continue
+					}
+				}
+				if job.JobType == task.ConnectionJob {
+					// the capture (re)connected, so its sequence may have started over
+					tracker.Reset()
+				}
+				status, missed := tracker.Check(job.Sequence)
+				if status == jobs.StatusDuplicate {
+					logger.Debug("Skipping a duplicate job", "sequence", job.Sequence)
+					bot.RedisInterface.RecordJob(connectCode, jobs.NewRecord(job, status, nil))
+					continue
+				} else if status == jobs.StatusGap {
+					logger.Warn("Missed jobs", "missed", missed, "sequence", job.Sequence)
+				}
+				logger.Debug("Popped a job", "type", job.JobType, "payload", job.Payload)
 				bot.refreshGameLiveness(connectCode)
 				bot.RedisInterface.RefreshActiveGame(guildID, connectCode)
 
@@ -66,19 +112,18 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 					Payload:   job.Payload.(string),
 				}
 				correlatedUserID := ""
+				var processErr error
 				sett := bot.StorageInterface.GetGuildSettings(guildID)
 
 				switch job.JobType {
 				case task.ConnectionJob:
+					// with signed links, the connection was already verified
+					connected, _ := capture.ParseConnection(job.Payload.(string))
 					lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
 					for lock == nil {
 						lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
 					}
-					if job.Payload == "true" {
-						dgs.Linked = true
-					} else {
-						dgs.Linked = false
-					}
+					dgs.Linked = connected
 					dgs.ConnectCode = connectCode
 					bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
@@ -87,9 +132,9 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 
 				case task.LobbyJob:
 					var lobby game.Lobby
-					err = json.Unmarshal([]byte(job.Payload.(string)), &lobby)
-					if err != nil {
-						log.Println(err)
+					processErr = json.Unmarshal([]byte(job.Payload.(string)), &lobby)
+					if processErr != nil {
+						logger.Warn("Couldn't parse a capture event", "type", job.JobType, "err", processErr)
 						break
 					}
 
@@ -97,19 +142,21 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 				case task.StateJob:
 					num, err := strconv.ParseInt(job.Payload.(string), 10, 64)
 					if err != nil {
-						log.Println(err)
+						logger.Warn("Couldn't parse a capture event", "type", job.JobType, "err", err)
+						processErr = err
 						break
 					}
 
 					bot.processTransition(game.Phase(num), dgsRequest)
 				case task.PlayerJob:
 					var player game.Player
-					err = json.Unmarshal([]byte(job.Payload.(string)), &player)
-					if err != nil {
-						log.Println(err)
+					processErr = json.Unmarshal([]byte(job.Payload.(string)), &player)
+					if processErr != nil {
+						logger.Warn("Couldn't parse a capture event", "type", job.JobType, "err", processErr)
 						break
 					}
 					if player.Color > 17 || player.Color < 0 {
+						processErr = fmt.Errorf("invalid player color %d", player.Color)
 						break
 					}
 
@@ -128,15 +175,16 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 							},
 						))
 						metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+						processErr = err
 					}
 					correlatedUserID = userID
 				case task.GameOverJob:
 					var gameOverResult game.Gameover
 					// log.Println("Successfully identified game over event:")
 					// log.Println(job.Payload)
-					err := json.Unmarshal([]byte(job.Payload.(string)), &gameOverResult)
-					if err != nil {
-						log.Println(err)
+					processErr = json.Unmarshal([]byte(job.Payload.(string)), &gameOverResult)
+					if processErr != nil {
+						logger.Warn("Couldn't parse a capture event", "type", job.JobType, "err", processErr)
 						break
 					}
 
@@ -190,6 +238,11 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 						bot.RedisInterface.SetDiscordGameState(dgs, lock)
 					}
 				}
+				if processErr != nil {
+					status = jobs.StatusFailed
+				}
+				bot.RedisInterface.RecordJob(connectCode, jobs.NewRecord(job, status, processErr))
+
 				if job.JobType != task.ConnectionJob {
 					go func(userID string, ge storage.PostgresGameEvent) {
 						dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
@@ -198,17 +251,17 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 							if userID != "" {
 								num, err := strconv.ParseUint(userID, 10, 64)
 								if err != nil {
-									log.Println(err)
+									dgs.logger().Warn("Couldn't parse the user of a game event", "err", err)
 									ge.UserID = nil
 								} else {
 									ge.UserID = &num
 								}
-								log.Printf("Adding postgres event with user id %d\n", ge.UserID)
+								dgs.logger().Debug("Adding a game event to Postgres", "user", userID)
 							}
 
 							err := bot.PostgresInterface.AddEvent(&ge)
 							if err != nil {
-								log.Println(err)
+								dgs.logger().Error("Couldn't add a game event to Postgres", "err", err)
 							}
 						}
 					}(correlatedUserID, gameEvent)
@@ -217,10 +270,10 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 
 		case <-timer.C:
 			timer.Stop()
-			log.Printf("Killing game w/ code %s after %d seconds of inactivity!\n", connectCode, bot.captureTimeout)
+			logger.Info("Ending the game because the capture was inactive", "timeout_seconds", bot.captureTimeout)
 			err := notify.Close()
 			if err != nil {
-				log.Println(err)
+				logger.Warn("Couldn't close the capture event subscription", "err", err)
 			}
 			go bot.forceEndGame(dgsRequest)
 			bot.ChannelsMapLock.Lock()
@@ -228,13 +281,15 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 			bot.ChannelsMapLock.Unlock()
 
 			return
-		case <-endGameChannel:
-			log.Println("Redis subscriber received kill signal, closing all pubsubs")
+		case end := <-endGameChannel:
+			logger.Info("Ending the game; closing the capture event subscription", "handover", !end)
 			err := notify.Close()
 			if err != nil {
-				log.Println(err)
+				logger.Warn("Couldn't close the capture event subscription", "err", err)
+			}
+			if end {
+				bot.forceEndGame(dgsRequest)
 			}
-			bot.forceEndGame(dgsRequest)
 			return
 		}
 	}
@@ -253,31 +308,41 @@ func getWinners(dgs GameState, gameOver game.Gameover) []winnerRecord {
 		gameOver.GameOverReason == game.ImpostorBySabotage ||
 		gameOver.GameOverReason == game.ImpostorDisconnect
 
+	// the results only have names, so a name shared by an imposter and a crewmate can't be credited to either
+	impostors := make(map[string]bool, len(gameOver.PlayerInfos))
+	ambiguous := make(map[string]bool)
+	for _, v := range gameOver.PlayerInfos {
+		if isImpostor, ok := impostors[v.Name]; ok && isImpostor != v.IsImpostor {
+			ambiguous[v.Name] = true
+		}
+		impostors[v.Name] = v.IsImpostor
+	}
+
 	for _, player := range dgs.UserData {
-		if player.GetPlayerName() != amongus.UnlinkedPlayerName {
-			for _, v := range gameOver.PlayerInfos {
-				// only override for the imposters
-				if player.GetPlayerName() == v.Name {
-					if (v.IsImpostor && imposterWin) || (!v.IsImpostor && !imposterWin) {
-						role := game.CrewmateRole
-						if v.IsImpostor {
-							role = game.ImposterRole
-						}
-						winners = append(winners, winnerRecord{
-							userID: player.User.UserID,
-							role:   role,
-						})
-					}
-				}
-			}
+		name := player.GetPlayerName()
+		if name == amongus.UnlinkedPlayerName || ambiguous[name] {
+			continue
+		}
+		isImpostor, ok := impostors[name]
+		if !ok || isImpostor != imposterWin {
+			continue
 		}
+		role := game.CrewmateRole
+		if isImpostor {
+			role = game.ImposterRole
+		}
+		winners = append(winners, winnerRecord{
+			userID: player.User.UserID,
+			role:   role,
+		})
 	}
 	return winners
 }
 
 func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player, dgsRequest GameStateRequest) (bool, string, *GameState, error) {
-	var err error
 	if player.Name != "" {
+		// look the profiles up before locking the game, so a slow Postgres doesn't hold up the game
+		profiles := bot.userProfiles(player.Name)
 		lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
 		for lock == nil {
 			lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
@@ -288,22 +353,18 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 
 		if player.Disconnected || player.Action == game.LEFT {
 			if player.Disconnected {
-				log.Println("I detected that " + player.Name + " disconnected, I'm purging their player data!")
-				dgs.ClearPlayerDataByPlayerName(player.Name)
+				dgs.logger().Info("Purging the data of a player that disconnected", "player", player.Name)
+				dgs.ClearPlayerDataByPlayer(amongus.PlayerData{Name: player.Name, Color: player.Color})
 			}
 			_, _, data := dgs.GameData.UpdatePlayer(player)
 
-			userID := dgs.AttemptPairingByMatchingNames(data)
-			// try pairing via the cached usernames
-			if userID == "" {
-				var uids map[string]interface{}
-				uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
-			} else {
+			// no point asking someone to confirm they're a player who just left
+			userID, byName, err := bot.pairPlayer(dgs, data, profiles, sett, false)
+			if byName {
 				err = bot.applyToSingle(dgs, userID, false, false)
 			}
 
-			dgs.GameData.ClearPlayerData(player.Name)
+			dgs.GameData.ClearPlayerData(player.Color)
 
 			// only update the message if we're not in the tasks phase (info leaks)
 			if dgs.GameData.GetPhase() != game.TASKS {
@@ -312,31 +373,26 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 
 			return true, userID, dgs, err
 		}
+		if player.Action == game.CHANGECOLOR {
+			if oldColor, moved := dgs.GameData.MovePlayer(player.Name, player.Color); moved {
+				dgs.RelinkPlayerColor(player.Name, oldColor, player.Color)
+			}
+		}
 		updated, isAliveUpdated, data := dgs.GameData.UpdatePlayer(player)
 		switch {
 		case player.Action == game.JOINED:
-			log.Println("Detected a player joined, refreshing User data mappings")
-			userID := dgs.AttemptPairingByMatchingNames(data)
-			if userID == "" {
-				var uids map[string]interface{}
-				uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
-			}
+			dgs.logger().Debug("A player joined; refreshing the user data mappings", "player", player.Name)
+			userID, _, err := bot.pairPlayer(dgs, data, profiles, sett, true)
 			bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
 			return true, userID, dgs, err
 		case updated:
-			userID := dgs.AttemptPairingByMatchingNames(data)
-			if userID == "" {
-				var uids map[string]interface{}
-				uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
-			}
+			userID, _, err := bot.pairPlayer(dgs, data, profiles, sett, true)
 			if isAliveUpdated && dgs.GameData.GetPhase() == game.TASKS {
 				if sett.GetUnmuteDeadDuringTasks() || player.Action == game.EXILED {
 					bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
 					return true, userID, dgs, err
 				}
-				log.Println("NOT updating the discord status message; would leak info")
+				dgs.logger().Debug("Not updating the game message; it would leak who died")
 				return false, userID, dgs, err
 			}
 			bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
@@ -370,7 +426,7 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 		dgs.MatchStartUnix = matchStart
 		gameID := startGameInPostgres(*dgs, bot.PostgresInterface)
 		dgs.MatchID = int64(gameID)
-		log.Printf("New match has begun. ID %d and starttime %d\n", gameID, matchStart)
+		dgs.logger().Info("A new match began", "start", matchStart)
 	}
 
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
@@ -379,7 +435,7 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 		bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
 		err := bot.applyToAll(dgs, false, false)
 		if err != nil {
-			log.Println("Error in unmuting all users when returning to menu ", err)
+			dgs.logger().Error("Couldn't unmute everyone when returning to the menu", "err", err)
 		}
 		// on a gameover event from the capture, it's like going to the lobby; use that delay
 	case game.GAMEOVER:
@@ -432,7 +488,7 @@ func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
 	}
 	gid, err := strconv.ParseUint(dgs.GuildID, 10, 64)
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't parse the guild ID", "err", err)
 		return 0
 	}
 	pgame := &storage.PostgresGame{
@@ -445,14 +501,14 @@ func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
 	}
 	i, err := psql.AddInitialGame(pgame)
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't save the new match to Postgres", "err", err)
 	}
 	return i
 }
 
 func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver game.Gameover) {
 	if dgs.MatchID < 0 || dgs.MatchStartUnix < 0 {
-		log.Println("dgs match id or start time is <0; not dumping game to Postgres")
+		dgs.logger().Warn("Not saving the game to Postgres; it has no match ID or start time")
 		return
 	}
 	end := time.Now().Unix()
@@ -466,26 +522,26 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 
 	for _, v := range dgs.UserData {
 		if v.GetPlayerName() != amongus.UnlinkedPlayerName {
-			inGameData, found := dgs.GameData.GetByName(v.GetPlayerName())
+			inGameData, found := dgs.GameData.GetPlayer(v.GetPlayerName(), v.GetPlayerColor())
 			if !found {
-				log.Println("No game data found for that player")
+				dgs.logger().Warn("No game data found for a player", "player", v.GetPlayerName())
 				continue
 			}
 
 			uid, err := strconv.ParseUint(v.User.UserID, 10, 64)
 			if err != nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't parse a player's user ID", "err", err)
 				continue
 			}
 			gid, err := strconv.ParseUint(dgs.GuildID, 10, 64)
 			if err != nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't parse the guild ID", "err", err)
 				continue
 			}
 
 			puser, err := psql.EnsureUserExists(uid)
 			if err != nil || puser == nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't save a player to Postgres", "user", v.User.UserID, "err", err)
 				continue
 			}
 
@@ -514,10 +570,10 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 			})
 		}
 	}
-	log.Printf("Game %d has been completed and recorded in postgres\n", dgs.MatchID)
-
 	err := psql.UpdateGameAndPlayers(dgs.MatchID, int16(gameOver.GameOverReason), end, userGames)
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't save the finished game to Postgres", "err", err)
+		return
 	}
+	dgs.logger().Info("Saved the finished game to Postgres", "players", len(userGames))
 }
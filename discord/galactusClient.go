@@ -12,7 +12,6 @@ import (
 	"github.com/bsm/redislock"
 	"github.com/go-redis/redis/v8"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 )
@@ -59,7 +58,7 @@ func (gc *GalactusClient) ModifyUsers(guildID, connectCode string, request task.
 		return nil, err
 	}
 
-	log.Println(request)
+	gameLogger(guildID, connectCode).Debug("Modifying users", "request", request)
 
 	resp, err := gc.client.Post(fullURL, "application/json", bytes.NewBuffer(jBytes))
 	if err != nil {
@@ -70,12 +69,12 @@ func (gc *GalactusClient) ModifyUsers(guildID, connectCode string, request task.
 	mds := task.MuteDeafenSuccessCounts{}
 	jBytes, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Println(err)
+		gameLogger(guildID, connectCode).Error("Couldn't read the response from Galactus", "err", err)
 		return &mds, err
 	}
 	err = json.Unmarshal(jBytes, &mds)
 	if err != nil {
-		log.Println(err)
+		gameLogger(guildID, connectCode).Error("Couldn't parse the response from Galactus", "err", err)
 		return &mds, err
 	}
 	if resp.StatusCode != http.StatusOK {
@@ -93,3 +92,20 @@ func (gc *GalactusClient) VerifyPremiumMembership(guildID uint64, prem premium.T
 	}
 	return nil
 }
+
+// Ping checks that Galactus is reachable
+func (gc *GalactusClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gc.Address+"/", nil)
+	if err != nil {
+		return err
+	}
+	r, err := gc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("galactus returned status code %d", r.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package discord
+
+import (
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	gamePauseID              = "game-pause"
+	gameRefreshID            = "game-refresh"
+	gameUnmuteAllID          = "game-unmute-all"
+	gameUnmuteAllConfirmedID = "game-unmute-all-confirmed"
+	gameEndID                = "game-end"
+	gameEndConfirmedID       = "game-end-confirmed"
+	gameControlCanceledID    = "game-control-canceled"
+)
+
+// gameControlComponents are buttons on the game state message for the same things as /pause, /refresh,
+// /debug unmute-all and /end
+func gameControlComponents(sett *settings.GuildSettings) discordgo.ActionsRow {
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				CustomID: gamePauseID,
+				Style:    discordgo.SecondaryButton,
+				Label: sett.LocalizeMessage(&i18n.Message{
+					ID:    "gameControls.button.pause",
+					Other: "Pause/Resume",
+				}),
+				Emoji: discordgo.ComponentEmoji{Name: "⏯️"},
+			},
+			discordgo.Button{
+				CustomID: gameRefreshID,
+				Style:    discordgo.SecondaryButton,
+				Label: sett.LocalizeMessage(&i18n.Message{
+					ID:    "gameControls.button.refresh",
+					Other: "Refresh",
+				}),
+				Emoji: discordgo.ComponentEmoji{Name: "🔄"},
+			},
+			discordgo.Button{
+				CustomID: gameUnmuteAllID,
+				Style:    discordgo.SecondaryButton,
+				Label: sett.LocalizeMessage(&i18n.Message{
+					ID:    "gameControls.button.unmuteAll",
+					Other: "Unmute All",
+				}),
+				Emoji: discordgo.ComponentEmoji{Name: "🔊"},
+			},
+			discordgo.Button{
+				CustomID: gameEndID,
+				Style:    discordgo.DangerButton,
+				Label: sett.LocalizeMessage(&i18n.Message{
+					ID:    "gameControls.button.end",
+					Other: "End",
+				}),
+				Emoji: discordgo.ComponentEmoji{Name: "⏹️"},
+			},
+		},
+	}
+}
+
+// handleGameControl handles the game control buttons, and the confirmations for the destructive ones. handled is false
+// if the component isn't one of them
+func (bot *Bot) handleGameControl(i *discordgo.InteractionCreate, gsr GameStateRequest, sett, userSett *settings.GuildSettings) (resp *discordgo.InteractionResponse, handled bool) {
+	customID := i.MessageComponentData().CustomID
+	switch customID {
+	case gamePauseID, gameUnmuteAllID, gameUnmuteAllConfirmedID, gameEndID, gameEndConfirmedID, gameRefreshID, gameControlCanceledID:
+	default:
+		return nil, false
+	}
+
+	switch customID {
+	case gamePauseID:
+		return bot.pauseGame(gsr, sett, userSett), true
+	case gameRefreshID:
+		if bot.RefreshGameStateMessage(gsr, sett) {
+			// the message with the button is gone, so there's nothing to update
+			return command.PrivateResponse(ThumbsUp), true
+		}
+		return command.NoGameResponse(userSett), true
+	case gameUnmuteAllID:
+		return gameControlConfirmation(gameUnmuteAllConfirmedID, userSett.LocalizeMessage(&i18n.Message{
+			ID:    "gameControls.unmuteAll.confirmation",
+			Other: "⚠️**Are you sure?**⚠️\nEveryone in the game will be unmuted and undeafened, even if they're dead",
+		}), userSett.LocalizeMessage(&i18n.Message{
+			ID:    "gameControls.unmuteAll.button.proceed",
+			Other: "Unmute All",
+		}), userSett), true
+	case gameUnmuteAllConfirmedID:
+		return updateMessageResponse(bot.unmuteAll(gsr, userSett)), true
+	case gameEndID:
+		return gameControlConfirmation(gameEndConfirmedID, userSett.LocalizeMessage(&i18n.Message{
+			ID:    "gameControls.end.confirmation",
+			Other: "⚠️**Are you sure?**⚠️\nDo you really want to end the game? Everyone will be unmuted",
+		}), userSett.LocalizeMessage(&i18n.Message{
+			ID:    "gameControls.end.button.proceed",
+			Other: "End Game",
+		}), userSett), true
+	case gameEndConfirmedID:
+		return updateMessageResponse(bot.endGame(gsr, userSett)), true
+	default:
+		return resetCancelResponse(userSett), true
+	}
+}
+
+func gameControlConfirmation(confirmedID, content, proceedLabel string, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:      1 << 6, //private message
+			Content:    content,
+			Components: confirmationComponents(confirmedID, gameControlCanceledID, proceedLabel, sett),
+		},
+	}
+}
+
+// updateMessageResponse replaces the (private) message the component was on with the response's content, removing
+// the components so they can't be used again
+func updateMessageResponse(resp *discordgo.InteractionResponse) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    resp.Data.Content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}
+}
+
+func (bot *Bot) pauseGame(gsr GameStateRequest, sett, userSett *settings.GuildSettings) *discordgo.InteractionResponse {
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
+	if lock == nil {
+		guildLogger(gsr.GuildID).Warn("No lock could be obtained when pausing game", "channel", gsr.TextChannel)
+		return command.DeadlockGameStateResponse(command.Pause.Name, userSett)
+	}
+	if !dgs.GameStateMsg.Exists() {
+		bot.RedisInterface.SetDiscordGameState(nil, lock)
+		return command.NoGameResponse(userSett)
+	}
+
+	dgs.Running = !dgs.Running
+
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	var err error
+	// if we paused the game, unmute/undeafen all players
+	if !dgs.Running {
+		err = bot.applyToAll(dgs, false, false)
+	}
+	bot.DispatchRefreshOrEdit(dgs, gsr, sett)
+	if err != nil {
+		return command.PrivateErrorResponse(command.Pause.Name, err, userSett)
+	}
+	return command.PrivateResponse(ThumbsUp)
+}
+
+func (bot *Bot) endGame(gsr GameStateRequest, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+	if dgs == nil {
+		return command.DeadlockGameStateResponse(command.End.Name, sett)
+	}
+	if !dgs.GameStateMsg.Exists() {
+		return command.NoGameResponse(sett)
+	}
+
+	if v, ok := bot.EndGameChannels[dgs.ConnectCode]; ok {
+		v <- true
+	}
+	delete(bot.EndGameChannels, dgs.ConnectCode)
+
+	err := bot.applyToAll(dgs, false, false)
+	if err != nil {
+		return command.PrivateErrorResponse(command.End.Name, err, sett)
+	}
+	return command.PrivateResponse(ThumbsUp)
+}
+
+func (bot *Bot) unmuteAll(gsr GameStateRequest, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+	if dgs == nil {
+		return command.DeadlockGameStateResponse(command.UnmuteAll, sett)
+	}
+	err := bot.applyToAll(dgs, false, false)
+	if err != nil {
+		return command.PrivateErrorResponse(command.UnmuteAll, err, sett)
+	}
+	return command.PrivateResponse(ThumbsUp)
+}
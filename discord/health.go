@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/automuteus/automuteus/metrics"
+)
+
+// Discord sends a heartbeat about every 41 seconds; missing a few means the gateway connection is dead
+const gatewayHeartbeatStale = 2 * time.Minute
+
+// healthChecker checks everything the bot needs. Games can't run without Redis, Galactus or the gateway, but they
+// keep working while Postgres is down, only without stats and premium
+func (bot *Bot) healthChecker() *metrics.HealthChecker {
+	return metrics.NewHealthChecker(bot.PrimarySession.ShardID, bot.PrimarySession.ShardCount,
+		metrics.Check{
+			Name:     "redis",
+			Critical: true,
+			Check: func(ctx context.Context) error {
+				return bot.RedisInterface.client.Ping(ctx).Err()
+			},
+		},
+		metrics.Check{
+			Name: "postgres",
+			Check: func(ctx context.Context) error {
+				return bot.PostgresInterface.Pool.Ping(ctx)
+			},
+		},
+		metrics.Check{
+			Name:     "galactus",
+			Critical: true,
+			Check:    bot.GalactusClient.Ping,
+		},
+		metrics.Check{
+			Name:     "gateway",
+			Critical: true,
+			Check: func(ctx context.Context) error {
+				return bot.checkGateway()
+			},
+		},
+	)
+}
+
+// checkGateway checks that the session is connected to the gateway, and that Discord still acknowledges its heartbeats
+func (bot *Bot) checkGateway() error {
+	s := bot.PrimarySession
+	s.RLock()
+	ready, lastAck := s.DataReady, s.LastHeartbeatAck
+	s.RUnlock()
+	if !ready {
+		return errors.New("not connected to the gateway")
+	}
+	if since := time.Since(lastAck); since > gatewayHeartbeatStale {
+		return fmt.Errorf("no heartbeat was acknowledged for %s", since.Round(time.Second))
+	}
+	return nil
+}
@@ -1,29 +1,130 @@
 package discord
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"log"
-	"math/rand"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/automuteus/automuteus/capture"
+	jobs "github.com/automuteus/automuteus/job"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/bwmarrin/discordgo"
+	"github.com/skip2/go-qrcode"
 )
 
-func generateConnectCode(guildID string) string {
-	h := sha256.New()
-	h.Write([]byte(guildID))
+// maxConnectCodeAttempts is how many random codes we try before giving up on finding one that isn't in use
+const maxConnectCodeAttempts = 5
 
-	// add some randomness
-	h.Write([]byte(fmt.Sprintf("%f", rand.Float64())))
-	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))[0:8])
+func (bot *Bot) generateConnectCode() string {
+	for i := 0; i < maxConnectCodeAttempts; i++ {
+		code, err := capture.GenerateConnectCode()
+		if err != nil {
+			logging.Default().Error("Couldn't generate a connect code", "err", err)
+			continue
+		}
+		if bot.RedisInterface.IsActiveConnectCode(code) {
+			codeLogger(code).Debug("Generated connect code is already in use, retrying")
+			continue
+		}
+		// a collision is very unlikely, so don't fail to start games just because Postgres is unavailable
+		personal, err := storage.IsPersonalConnectCode(bot.PostgresInterface, code)
+		if err != nil {
+			codeLogger(code).Error("Couldn't check whether the connect code is a personal one", "err", err)
+		} else if personal {
+			codeLogger(code).Debug("Generated connect code is reserved as a personal code, retrying")
+			continue
+		}
+		return code
+	}
+	return ""
+}
+
+// issueCaptureURL forms the capture link for a game, signing it if a secret is configured. Issuing a new signed link
+// revokes any link previously issued for the same connect code
+func (bot *Bot) issueCaptureURL(connectCode string) (hyperlink, minimalURL string) {
+	if bot.captureSigner == nil {
+		return formCaptureURL(bot.url, connectCode, "")
+	}
+	nonce, err := capture.NewNonce()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't make a capture link nonce", "err", err)
+		return formCaptureURL(bot.url, connectCode, "")
+	}
+	ttl := bot.captureSigner.TTL()
+	err = bot.RedisInterface.SetCaptureLinkNonce(connectCode, nonce, ttl)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't save the capture link nonce", "err", err)
+	}
+	token := bot.captureSigner.Sign(connectCode, nonce, time.Now().Add(ttl))
+	return formCaptureURL(bot.url, connectCode, token)
+}
+
+// VerifyCaptureToken checks a token from a capture link is signed, unexpired, and is the most recent link issued for
+// the connect code, and returns the link's nonce. It always succeeds if capture links aren't signed
+func (bot *Bot) VerifyCaptureToken(connectCode, token string) (string, error) {
+	if bot.captureSigner == nil {
+		return "", nil
+	}
+	nonce, err := bot.captureSigner.Verify(connectCode, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if bot.RedisInterface.GetCaptureLinkNonce(connectCode) != nonce {
+		return "", capture.ErrRevokedToken
+	}
+	// the link only expires for captures that haven't connected yet; a game can outlast it
+	bot.RedisInterface.PersistCaptureLinkNonce(connectCode)
+	return nonce, nil
+}
+
+// connectCaptureSession verifies a connection event against the capture link, and saves the session it results in
+func (bot *Bot) connectCaptureSession(connectCode string, session capture.Session, job jobs.Job) (capture.Session, error) {
+	next, _, err := session.Connect(job.Session, job.Payload.(string), func(token string) (string, error) {
+		return bot.VerifyCaptureToken(connectCode, token)
+	})
+	if err != nil {
+		return session, err
+	}
+	bot.RedisInterface.SetCaptureSession(connectCode, next)
+	return next, nil
+}
+
+// captureLinkRevoked is whether the link with the nonce was revoked by /end or /new regenerate-link since a capture
+// connected with it
+func (bot *Bot) captureLinkRevoked(connectCode, nonce string) bool {
+	return bot.RedisInterface.GetCaptureLinkNonce(connectCode) != nonce
+}
+
+func (bot *Bot) isPersonalConnectCode(connectCode string) bool {
+	personal, err := storage.IsPersonalConnectCode(bot.PostgresInterface, connectCode)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't check whether the connect code is a personal one", "err", err)
+	}
+	return personal
+}
+
+// captureQRCode encodes a capture link as a PNG QR code, so it can be scanned from a second screen
+func captureQRCode(hyperlink string) []byte {
+	if !strings.HasPrefix(hyperlink, "aucapture://") {
+		// formCaptureURL returns an error message instead of a link if the HOST is invalid
+		return nil
+	}
+	png, err := qrcode.Encode(hyperlink, qrcode.Medium, 256)
+	if err != nil {
+		logging.Default().Error("Couldn't encode the capture link QR code", "err", err)
+		return nil
+	}
+	return png
 }
 
 var urlregex = regexp.MustCompile(`^http(?P<secure>s?)://(?P<host>[\w.-]+)(?::(?P<port>\d+))?/?$`)
 
-func formCaptureURL(url, connectCode string) (hyperlink, minimalURL string) {
+// formCaptureURL builds the aucapture:// link for a game. If token is non-empty, it's embedded so the broker can
+// verify the link was issued by us and hasn't expired or been revoked
+func formCaptureURL(url, connectCode, token string) (hyperlink, minimalURL string) {
 	if match := urlregex.FindStringSubmatch(url); match != nil {
 		secure := match[urlregex.SubexpIndex("secure")] == "s"
 		host := match[urlregex.SubexpIndex("host")]
@@ -45,6 +146,13 @@ func formCaptureURL(url, connectCode string) (hyperlink, minimalURL string) {
 		}
 
 		hyperlink = fmt.Sprintf("aucapture://%s%s/%s%s", host, port, connectCode, insecure)
+		if token != "" {
+			if insecure == "" {
+				hyperlink += "?token=" + token
+			} else {
+				hyperlink += "&token=" + token
+			}
+		}
 		minimalURL = fmt.Sprintf("%s%s%s", protocol, host, port)
 	} else {
 		hyperlink = "Invalid HOST provided (should resemble something like `http://localhost:8123`)"
@@ -67,7 +175,7 @@ func sendEmbedWithComponents(s *discordgo.Session, channelID string, message *di
 	}
 	msg, err := s.ChannelMessageSendComplex(channelID, &complexMsg)
 	if err != nil {
-		log.Println(err)
+		logging.Default().Error("Couldn't send the message", "channel", channelID, "err", err)
 	}
 	return msg
 }
@@ -76,7 +184,7 @@ func editMessageEmbed(s *discordgo.Session, channelID string, messageID string,
 	me := discordgo.NewMessageEdit(channelID, messageID).SetEmbed(message)
 	msg, err := s.ChannelMessageEditComplex(me)
 	if err != nil {
-		log.Println("Error when attempting to edit complex message", err)
+		logging.Default().Error("Couldn't edit the message", "channel", channelID, "message", messageID, "err", err)
 	}
 	return msg
 }
@@ -84,3 +192,27 @@ func editMessageEmbed(s *discordgo.Session, channelID string, messageID string,
 func matchIDCode(connectCode string, matchID int64) string {
 	return fmt.Sprintf("%s:%d", connectCode, matchID)
 }
+
+// getLinksForUser maps each of the in-game names the user is remembered by to the other users who share that name
+func (bot *Bot) getLinksForUser(guildID, userID string) (map[string][]string, error) {
+	names, err := bot.RedisInterface.GetUsernameOrUserIDMappings(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string][]string, len(names))
+	for name := range names {
+		links[name] = []string{}
+		uids, err := bot.RedisInterface.GetUsernameOrUserIDMappings(guildID, name)
+		if err != nil {
+			guildLogger(guildID).Error("Couldn't fetch the users for a name", "err", err)
+			continue
+		}
+		for uid := range uids {
+			if uid != userID {
+				links[name] = append(links[name], uid)
+			}
+		}
+		sort.Strings(links[name])
+	}
+	return links, nil
+}
@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/automuteus/automuteus/logging"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// debugGuildsKey are the guilds the owners turned debug logging on for, on every shard
+	debugGuildsKey      = "automuteus:log:debug:guilds"
+	debugGuildsInterval = 30 * time.Second
+)
+
+// guildLogger has the guild on every line
+func guildLogger(guildID string) *logging.Logger {
+	return logging.Default().With(logging.GuildKey, guildID)
+}
+
+// gameLogger has the game's guild and connect code on every line
+func gameLogger(guildID, connectCode string) *logging.Logger {
+	return guildLogger(guildID).With(logging.ConnectCodeKey, connectCode)
+}
+
+// codeLogger has the connect code on every line, for when the guild isn't known
+func codeLogger(connectCode string) *logging.Logger {
+	return logging.Default().With(logging.ConnectCodeKey, connectCode)
+}
+
+// logger has the game's guild, connect code and match ID, if a match started, on every line
+func (dgs *GameState) logger() *logging.Logger {
+	logger := gameLogger(dgs.GuildID, dgs.ConnectCode)
+	if dgs.MatchID > 0 {
+		logger = logger.With(logging.MatchIDKey, dgs.MatchID)
+	}
+	return logger
+}
+
+// debugGuildsWorker regularly picks up the guilds the owners turned debug logging on or off for, on any shard
+func (bot *Bot) debugGuildsWorker() {
+	for {
+		bot.refreshDebugGuilds()
+		time.Sleep(debugGuildsInterval)
+	}
+}
+
+func (bot *Bot) refreshDebugGuilds() {
+	guildIDs, err := bot.RedisInterface.GetDebugGuilds()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	logging.Default().SetDebugGuilds(append(guildIDs, bot.debugGuildIDs...))
+}
+
+func (redisInterface *RedisInterface) SetGuildDebug(guildID string, enabled bool) error {
+	if enabled {
+		return redisInterface.client.SAdd(ctx, debugGuildsKey, guildID).Err()
+	}
+	return redisInterface.client.SRem(ctx, debugGuildsKey, guildID).Err()
+}
+
+func (redisInterface *RedisInterface) GetDebugGuilds() ([]string, error) {
+	guildIDs, err := redisInterface.client.SMembers(ctx, debugGuildsKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return guildIDs, err
+}
@@ -0,0 +1,53 @@
+package discord
+
+import (
+	"time"
+
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/bwmarrin/discordgo"
+)
+
+// maintenance mode is set in Redis for all shards, so each shard checks for it this often
+const maintenancePollInterval = 30 * time.Second
+
+// maintenanceWorker keeps the bot's presence and the health check up to date with maintenance mode
+func (bot *Bot) maintenanceWorker() {
+	first := true
+	wasMaintenance := false
+	for {
+		maintenance, eta := redis_common.GetMaintenance(bot.RedisInterface.client)
+		metrics.SetMaintenance(maintenance, eta)
+		if first || maintenance != wasMaintenance {
+			logging.Default().Info("Maintenance mode changed", "maintenance", maintenance)
+			bot.updatePresence(maintenance)
+		}
+		first, wasMaintenance = false, maintenance
+		time.Sleep(maintenancePollInterval)
+	}
+}
+
+// updatePresence shows that the bot isn't starting new games during maintenance, and what to use it with otherwise
+func (bot *Bot) updatePresence(maintenance bool) {
+	status := discordgo.UpdateStatusData{
+		IdleSince: nil,
+		Activities: []*discordgo.Activity{{
+			Name: bot.listeningTo,
+			Type: discordgo.ActivityTypeListening,
+		}},
+		AFK:    false,
+		Status: "",
+	}
+	if maintenance {
+		status.Activities[0] = &discordgo.Activity{
+			Name: "🛠️ maintenance; no new games",
+			Type: discordgo.ActivityTypeWatching,
+		}
+		status.Status = string(discordgo.StatusIdle)
+	}
+	err := bot.PrimarySession.UpdateStatusComplex(status)
+	if err != nil {
+		logging.Default().Error("Couldn't update the bot's presence", "err", err)
+	}
+}
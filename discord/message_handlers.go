@@ -2,7 +2,6 @@ package discord
 
 import (
 	"github.com/automuteus/utils/pkg/settings"
-	"log"
 	"strconv"
 	"time"
 
@@ -65,7 +64,7 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 
 	tracked := m.ChannelID != "" && dgs.VoiceChannel == m.ChannelID
 
-	auData, found := dgs.GameData.GetByName(userData.InGameName)
+	auData, found := dgs.GameData.GetPlayer(userData.GetPlayerName(), userData.GetPlayerColor())
 
 	var isAlive bool
 
@@ -102,7 +101,7 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 			}
 			mdsc, err := bot.GalactusClient.ModifyUsers(m.GuildID, dgs.ConnectCode, req, voiceLock)
 			if err != nil {
-				log.Println("error received from galactus for modifyUsers: ", err.Error())
+				dgs.logger().Error("Couldn't modify the users through Galactus", "err", err)
 			} else if mdsc != nil {
 				go RecordDiscordRequestsByCounts(bot.RedisInterface.client, mdsc)
 			}
@@ -118,7 +117,7 @@ func (bot *Bot) handleGameStartMessage(guildID, textChannelID, voiceChannelID, u
 		ConnectCode: connCode,
 	})
 	if lock == nil {
-		log.Println("Couldn't obtain lock for DGS on game start...")
+		guildLogger(guildID).Warn("No lock could be obtained when starting a game", "channel", textChannelID)
 		return
 	}
 	dgs.GameData.Reset()
@@ -138,7 +137,7 @@ func (bot *Bot) handleGameStartMessage(guildID, textChannelID, voiceChannelID, u
 		}
 	}
 
-	_ = dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), textChannelID, userID)
+	_ = dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), textChannelID, userID, sett)
 
 	// release the lock
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
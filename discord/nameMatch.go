@@ -0,0 +1,282 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	// custom IDs are of the form name-match:<yes/no>:<userID>:<color>:<in-game name>
+	nameMatchIDPrefix = "name-match:"
+	nameMatchYes      = "yes"
+	nameMatchNo       = "no"
+
+	// nameMatchPromptMinutes is how long an unanswered prompt stays in the channel
+	nameMatchPromptMinutes = 5
+
+	// profileCacheTTL is how long the profiles for an in-game name are kept, so player updates don't query Postgres
+	profileCacheTTL = time.Minute
+
+	// profilesChangedChannel tells every shard to forget the profiles it cached, when a user changes theirs
+	profilesChangedChannel = "automuteus:profiles:changed"
+)
+
+// profileCache holds the profiles registered for each in-game name, for players that are being updated constantly
+type profileCache struct {
+	lock    sync.Mutex
+	entries map[string]profileCacheEntry
+}
+
+type profileCacheEntry struct {
+	profiles []storage.UserProfile
+	expires  time.Time
+}
+
+func (pc *profileCache) get(name string) ([]storage.UserProfile, bool) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	entry, ok := pc.entries[name]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.profiles, true
+}
+
+func (pc *profileCache) set(name string, profiles []storage.UserProfile) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	now := time.Now()
+	if pc.entries == nil {
+		pc.entries = map[string]profileCacheEntry{}
+	}
+	for k, v := range pc.entries {
+		if now.After(v.expires) {
+			delete(pc.entries, k)
+		}
+	}
+	pc.entries[name] = profileCacheEntry{profiles: profiles, expires: now.Add(profileCacheTTL)}
+}
+
+// clear forgets every cached profile, for when a user changes theirs
+func (pc *profileCache) clear() {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	pc.entries = nil
+}
+
+// profilesChanged clears the profile cache on every shard, this one included
+func (bot *Bot) profilesChanged() {
+	bot.profiles.clear()
+	err := bot.RedisInterface.client.Publish(ctx, profilesChangedChannel, "").Err()
+	if err != nil {
+		logging.Default().Error("Couldn't tell the other shards the profiles changed", "err", err)
+	}
+}
+
+// profilesChangedWorker clears the profile cache whenever a user on any shard changes their profile
+func (bot *Bot) profilesChangedWorker() {
+	pubsub := bot.RedisInterface.client.Subscribe(context.Background(), profilesChangedChannel)
+	for range pubsub.Channel() {
+		bot.profiles.clear()
+	}
+}
+
+// userProfiles returns the profiles of every user who plays as the in-game name. It shouldn't be called with a game
+// state locked, because it can query Postgres
+func (bot *Bot) userProfiles(name string) []storage.UserProfile {
+	name = strings.ToLower(name)
+	if profiles, ok := bot.profiles.get(name); ok {
+		return profiles
+	}
+	profiles, err := storage.GetUserProfilesByInGameName(bot.PostgresInterface, name)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	bot.profiles.set(name, profiles)
+	return profiles
+}
+
+// pairPlayer attempts to link a player to a Discord user, first by the profiles registered for the player's name, then
+// by matching names and then by the names users have been linked to before. byName is true if the player was linked
+// by a profile or by matching names. If prompt is true, a user that only loosely matches the player's name is asked
+// to confirm it's them
+func (bot *Bot) pairPlayer(dgs *GameState, data amongus.PlayerData, profiles []storage.UserProfile, sett *settings.GuildSettings, prompt bool) (userID string, byName bool, err error) {
+	if dgs.GameData.IsDuplicateName(data.Name) {
+		// the name could be any of the players who share it, so only links made by color can be trusted
+		for id, v := range dgs.UserData {
+			if v.IsLinkedTo(data) {
+				return id, false, nil
+			}
+		}
+		return "", false, nil
+	}
+
+	if userID = dgs.AttemptPairingByProfiles(data, profiles); userID != "" {
+		return userID, true, nil
+	}
+
+	decision := dgs.AttemptPairingByMatchingNames(data)
+	if decision.Outcome == amongus.NameMatchLinked {
+		userID, byName = decision.UserID, true
+	} else {
+		var uids map[string]interface{}
+		uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, data.Name)
+		userID = dgs.AttemptPairingByUserIDs(data, uids)
+	}
+
+	// nothing to ask if we already know who it is from a previous link
+	if decision.Outcome == amongus.NameMatchPrompted && (userID != "" || !prompt) {
+		return userID, byName, err
+	}
+	if decision.Outcome == amongus.NameMatchPrompted {
+		if !bot.RedisInterface.MarkNameMatchPrompted(dgs.ConnectCode, decision.UserID, data.Name) {
+			// we've already asked this user about this player
+			return userID, byName, err
+		}
+		go bot.promptNameMatch(dgs.GameStateMsg.MessageChannelID, decision, sett)
+	}
+	// players get updated constantly; don't flood the history with "no match" for players someone is linked to
+	if decision.Outcome != amongus.NameMatchNone || userID == "" {
+		bot.RedisInterface.RecordNameMatch(dgs.ConnectCode, decision)
+	}
+	return userID, byName, err
+}
+
+func (bot *Bot) promptNameMatch(channelID string, decision amongus.NameMatchDecision, sett *settings.GuildSettings) {
+	color := game.GetColorStringForInt(decision.Color)
+	msg, err := bot.PrimarySession.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: sett.LocalizeMessage(&i18n.Message{
+			ID:    "nameMatch.prompt",
+			Other: "{{.User}}, are you **{{.Color}}** ({{.Name}}) in this game?",
+		}, map[string]interface{}{
+			"User":  discord.MentionByUserID(decision.UserID),
+			"Color": color,
+			"Name":  decision.PlayerName,
+		}),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						CustomID: nameMatchCustomID(nameMatchYes, decision.UserID, color, decision.PlayerName),
+						Style:    discordgo.SuccessButton,
+						Label: sett.LocalizeMessage(&i18n.Message{
+							ID:    "nameMatch.button.yes",
+							Other: "Yes, link me",
+						}),
+					},
+					discordgo.Button{
+						CustomID: nameMatchCustomID(nameMatchNo, decision.UserID, color, decision.PlayerName),
+						Style:    discordgo.SecondaryButton,
+						Label: sett.LocalizeMessage(&i18n.Message{
+							ID:    "nameMatch.button.no",
+							Other: "No",
+						}),
+					},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Users: []string{decision.UserID},
+		},
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 2)
+	MessageDeleteWorker(bot.PrimarySession, msg.ChannelID, msg.ID, time.Minute*nameMatchPromptMinutes)
+}
+
+// nameMatchCustomID includes the player's name, so a late answer can't link the user to whoever has the color by then
+func nameMatchCustomID(answer, userID, color, name string) string {
+	return fmt.Sprintf("%s%s:%s:%s:%s", nameMatchIDPrefix, answer, userID, color, name)
+}
+
+func parseNameMatchCustomID(customID string) (answer, userID, color, name string, ok bool) {
+	// the name is last, since it could contain a colon
+	parts := strings.SplitN(strings.TrimPrefix(customID, nameMatchIDPrefix), ":", 4)
+	if len(parts) != 4 {
+		return "", "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], parts[3], true
+}
+
+// handleNameMatchResponse links (or doesn't) the user who was asked whether they're a player
+func (bot *Bot) handleNameMatchResponse(i *discordgo.InteractionCreate, gsr GameStateRequest, sett, userSett *settings.GuildSettings) *discordgo.InteractionResponse {
+	answer, userID, color, name, ok := parseNameMatchCustomID(i.MessageComponentData().CustomID)
+	if !ok {
+		return nil
+	}
+	if userID != i.Member.User.ID {
+		return command.PrivateResponse(userSett.LocalizeMessage(&i18n.Message{
+			ID:    "nameMatch.wrongUser",
+			Other: "Only {{.User}} can answer this question",
+		}, map[string]interface{}{
+			"User": discord.MentionByUserID(userID),
+		}))
+	}
+
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
+	if lock == nil {
+		guildLogger(i.GuildID).Warn("No lock could be obtained when linking", "channel", i.ChannelID)
+		return command.DeadlockGameStateResponse(command.Link.Name, userSett)
+	}
+	data, found := dgs.GameData.GetByColor(color)
+	if !found {
+		data = amongus.PlayerData{Color: game.ColorStrings[color]}
+	}
+
+	var content string
+	if answer == nameMatchYes && data.Name != name {
+		bot.RedisInterface.SetDiscordGameState(nil, lock)
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "nameMatch.stale",
+			Other: "{{.Name}} isn't {{.Color}} anymore, so I didn't link you. Use `/link` to link yourself",
+		}, map[string]interface{}{
+			"Name":  name,
+			"Color": color,
+		})
+	} else if answer == nameMatchYes {
+		resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, color, sett)
+		if success {
+			bot.RedisInterface.SetDiscordGameState(dgs, lock)
+			bot.DispatchRefreshOrEdit(dgs, gsr, sett)
+			bot.RedisInterface.RecordNameMatch(dgs.ConnectCode, amongus.NewNameMatchDecision(data, userID, 1, amongus.NameMatchConfirmed))
+		} else {
+			bot.RedisInterface.SetDiscordGameState(nil, lock)
+		}
+		content = resp.Data.Content
+	} else {
+		bot.RedisInterface.SetDiscordGameState(nil, lock)
+		bot.RedisInterface.RecordNameMatch(dgs.ConnectCode, amongus.NewNameMatchDecision(data, userID, 0, amongus.NameMatchRejected))
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "nameMatch.rejected",
+			Other: "No problem, I won't link you to {{.Color}}",
+		}, map[string]interface{}{
+			"Color": color,
+		})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}
+}
@@ -0,0 +1,233 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/utils/pkg/rediskey"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	ownerAnnounceChannel = "automuteus:owner:announce"
+	ownerEndGameChannel  = "automuteus:owner:endgame"
+
+	shardStatusKey      = "automuteus:shards"
+	shardStatusInterval = 30 * time.Second
+	// a shard that hasn't reported its status for this long is considered down
+	shardStatusStale = 3 * shardStatusInterval
+)
+
+func (bot *Bot) isOwner(userID string) bool {
+	for _, id := range bot.ownerIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+type ownerEndGameMessage struct {
+	GuildID     string `json:"guildID"`
+	ConnectCode string `json:"connectCode"`
+}
+
+func (bot *Bot) ownerCommandHandler(i *discordgo.InteractionCreate, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	if !bot.isOwner(i.Member.User.ID) {
+		return command.InsufficientPermissionsResponse(sett)
+	}
+	params := command.GetOwnerParams(bot.PrimarySession, i.ApplicationCommandData().Options)
+	guildLogger(i.GuildID).Info("An owner used the owner command", "user", i.Member.User.ID, "action", params.Action)
+	client := bot.RedisInterface.client
+
+	switch params.Action {
+	case command.OwnerSoftbans + " " + command.OwnerSoftbansList:
+		banned, err := redis_common.SoftbannedUsers(client)
+		return command.OwnerSoftbansResponse(banned, err, sett)
+
+	case command.OwnerSoftbans + " " + command.OwnerSoftbansLift:
+		wasBanned, err := redis_common.LiftSoftban(client, params.UserID)
+		return command.OwnerLiftSoftbanResponse(params.UserID, wasBanned, err, sett)
+
+	case command.OwnerEndGame:
+		guildID, err := bot.RedisInterface.GetGuildForConnectCode(params.ConnectCode)
+		if err != nil || guildID == "" {
+			return command.OwnerEndGameResponse(params.ConnectCode, guildID, 0, err, sett)
+		}
+		// only the shard that's subscribed to the game can end it
+		msg, err := json.Marshal(ownerEndGameMessage{GuildID: guildID, ConnectCode: params.ConnectCode})
+		if err != nil {
+			return command.OwnerEndGameResponse(params.ConnectCode, guildID, 0, err, sett)
+		}
+		shards, err := client.Publish(ctx, ownerEndGameChannel, msg).Result()
+		return command.OwnerEndGameResponse(params.ConnectCode, guildID, shards, err, sett)
+
+	case command.OwnerShards:
+		statuses, err := bot.RedisInterface.GetShardStatuses()
+		return command.OwnerShardsResponse(statuses, shardStatusStale, err, sett)
+
+	case command.OwnerMaintenance:
+		err := redis_common.SetMaintenance(client, params.Enabled, params.ETA)
+		if err == nil {
+			// don't wait for the presence to be updated on the next poll
+			bot.updatePresence(params.Enabled)
+		}
+		return command.OwnerMaintenanceResponse(params.Enabled, params.ETA, err, sett)
+
+	case command.OwnerDebugLogging:
+		if _, err := strconv.ParseUint(params.GuildID, 10, 64); err != nil {
+			return command.OwnerDebugLoggingResponse(params.GuildID, params.Enabled, fmt.Errorf("%q isn't a guild ID", params.GuildID), sett)
+		}
+		err := bot.RedisInterface.SetGuildDebug(params.GuildID, params.Enabled)
+		if err == nil {
+			// the other shards pick it up on their next refresh
+			bot.refreshDebugGuilds()
+		}
+		return command.OwnerDebugLoggingResponse(params.GuildID, params.Enabled, err, sett)
+
+	case command.OwnerAnnounce:
+		shards, err := client.Publish(ctx, ownerAnnounceChannel, params.Message).Result()
+		return command.OwnerAnnounceResponse(shards, err, sett)
+	}
+	return nil
+}
+
+// ownerMessageWorker carries out the owner commands that every shard has to act on, for the guilds it's responsible for
+func (bot *Bot) ownerMessageWorker() {
+	pubsub := bot.RedisInterface.client.Subscribe(context.Background(), ownerAnnounceChannel, ownerEndGameChannel)
+	for msg := range pubsub.Channel() {
+		switch msg.Channel {
+		case ownerAnnounceChannel:
+			// posting in every guild takes a while; don't hold up the other owner commands
+			go bot.postAnnouncement(msg.Payload)
+		case ownerEndGameChannel:
+			var endGame ownerEndGameMessage
+			err := json.Unmarshal([]byte(msg.Payload), &endGame)
+			if err != nil {
+				logging.Default().Error("Couldn't parse an owner end game message", "err", err)
+				continue
+			}
+			bot.ChannelsMapLock.RLock()
+			_, subscribed := bot.EndGameChannels[endGame.ConnectCode]
+			bot.ChannelsMapLock.RUnlock()
+			if subscribed {
+				gameLogger(endGame.GuildID, endGame.ConnectCode).Info("Ending the game for an owner")
+				bot.endGame(GameStateRequest{
+					GuildID:     endGame.GuildID,
+					ConnectCode: endGame.ConnectCode,
+				}, bot.StorageInterface.GetGuildSettings(endGame.GuildID))
+			}
+		}
+	}
+}
+
+// postAnnouncement posts the announcement in the announcement channel of every guild on this shard that has one
+func (bot *Bot) postAnnouncement(announcement string) {
+	bot.PrimarySession.State.RLock()
+	guildIDs := make([]string, len(bot.PrimarySession.State.Guilds))
+	for i, g := range bot.PrimarySession.State.Guilds {
+		guildIDs[i] = g.ID
+	}
+	bot.PrimarySession.State.RUnlock()
+
+	posted := 0
+	for _, guildID := range guildIDs {
+		channelID := bot.StorageInterface.GetExtendedGuildSettings(guildID).AnnouncementChannelID
+		if channelID == "" {
+			continue
+		}
+		_, err := bot.PrimarySession.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: announcement,
+			// an announcement shouldn't ping @everyone or anyone else in every guild
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			guildLogger(guildID).Warn("Couldn't post the announcement", "channel", channelID, "err", err)
+			continue
+		}
+		posted++
+	}
+	logging.Default().Info("Posted the announcement", "posted", posted, "guilds", len(guildIDs))
+}
+
+// GetGuildForConnectCode finds the guild that has a game with the connect code, or returns "" if there isn't one
+func (redisInterface *RedisInterface) GetGuildForConnectCode(connectCode string) (string, error) {
+	prefix, suffix, _ := strings.Cut(rediskey.ConnectCodePtr("*", connectCode), "*")
+	// there's no index from connect codes to guilds, but this is rare enough to search for it
+	iter := redisInterface.client.Scan(ctx, 0, prefix+"*"+suffix, 0).Iterator()
+	if iter.Next(ctx) {
+		return strings.TrimSuffix(strings.TrimPrefix(iter.Val(), prefix), suffix), nil
+	}
+	return "", iter.Err()
+}
+
+// shardStatusWorker regularly reports this shard's status, for the owner command
+func (bot *Bot) shardStatusWorker() {
+	for {
+		bot.ChannelsMapLock.RLock()
+		activeGames := len(bot.EndGameChannels)
+		bot.ChannelsMapLock.RUnlock()
+		bot.PrimarySession.State.RLock()
+		guilds := len(bot.PrimarySession.State.Guilds)
+		bot.PrimarySession.State.RUnlock()
+
+		err := bot.RedisInterface.SetShardStatus(command.ShardStatus{
+			ShardID:     bot.PrimarySession.ShardID,
+			ShardCount:  bot.PrimarySession.ShardCount,
+			Guilds:      guilds,
+			ActiveGames: activeGames,
+			LatencyMs:   bot.PrimarySession.HeartbeatLatency().Milliseconds(),
+			Updated:     time.Now().Unix(),
+		})
+		if err != nil {
+			logging.Default().Error("Couldn't save the shard status", "err", err)
+		}
+		time.Sleep(shardStatusInterval)
+	}
+}
+
+func (redisInterface *RedisInterface) SetShardStatus(status command.ShardStatus) error {
+	jBytes, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return redisInterface.client.HSet(ctx, shardStatusKey, strconv.Itoa(status.ShardID), jBytes).Err()
+}
+
+func (redisInterface *RedisInterface) GetShardStatuses() ([]command.ShardStatus, error) {
+	all, err := redisInterface.client.HGetAll(ctx, shardStatusKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	statuses := make([]command.ShardStatus, 0, len(all))
+	for _, v := range all {
+		var status command.ShardStatus
+		err := json.Unmarshal([]byte(v), &status)
+		if err != nil {
+			logging.Default().Warn("Couldn't parse a shard status", "err", err)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// RemoveShardStatus forgets the shard when it shuts down cleanly, so it isn't reported as down
+func (redisInterface *RedisInterface) RemoveShardStatus(shardID int) {
+	err := redisInterface.client.HDel(ctx, shardStatusKey, strconv.Itoa(shardID)).Err()
+	if err != nil {
+		logging.Default().Error("Couldn't remove the shard status", "err", err)
+	}
+}
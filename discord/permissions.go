@@ -0,0 +1,49 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandPermitted is whether the member may use the command or subcommand with the permission key. The roles and
+// users the guild allowed with /settings permissions replace the default permission level, but admins may always use
+// everything so they can't lock themselves out. granted is whether the guild explicitly allowed the member
+func commandPermitted(key string, member *discordgo.Member, ext *storage.ExtendedGuildSettings, isAdmin, isPermissioned bool) (permitted, granted bool) {
+	for _, k := range command.PermissionKeyParents(key) {
+		if perm, ok := ext.CommandPermissions[k]; ok {
+			granted = perm.Allows(member.User.ID, member.Roles)
+			return isAdmin || granted, granted
+		}
+	}
+	switch command.DefaultPermission(key) {
+	case command.PermissionAdmin:
+		return isAdmin, false
+	case command.PermissionModerator:
+		return isAdmin || isPermissioned, false
+	default:
+		return true, false
+	}
+}
+
+// componentPermissionKey is the permission key of the command a component does the same as, or "" if anyone may use it
+func componentPermissionKey(customID string) string {
+	switch customID {
+	case gamePauseID:
+		return command.Pause.Name
+	case gameEndID, gameEndConfirmedID, gameUnmuteAllID, gameUnmuteAllConfirmedID:
+		// ending a game unmutes everyone too
+		return command.End.Name
+	case resetUserConfirmedID:
+		return command.Stats.Name + " " + setting.Clear + " " + command.User
+	case resetGuildConfirmedID:
+		return command.Stats.Name + " " + setting.Clear + " " + command.Guild
+	}
+	if strings.HasPrefix(customID, linkUserIDPrefix) {
+		return command.Link.Name
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/utils/pkg/premium"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// guild tiers are cached so rate limiting doesn't query Postgres for every interaction
+const guildTierCacheTTL = 10 * time.Minute
+
+func guildTierCacheKey(guildID string) string {
+	return "automuteus:ratelimit:tier:guild:" + guildID
+}
+
+// rateLimit takes a token for the command from the user's bucket, and from the guild's if guildID isn't empty
+func (bot *Bot) rateLimit(userID, guildID, command string) redis_common.RateLimitResult {
+	tier := strings.ToLower(premium.TierStrings[premium.FreeTier])
+	if guildID != "" {
+		tier = bot.guildTier(guildID)
+	}
+	return bot.rateLimiter.Allow(userID, guildID, tier, command)
+}
+
+// guildTier is the lowercase name of the guild's premium tier, or "free" if it expired
+func (bot *Bot) guildTier(guildID string) string {
+	client := bot.RedisInterface.client
+	if tier, err := client.Get(ctx, guildTierCacheKey(guildID)).Result(); err == nil {
+		return tier
+	}
+	prem, days, err := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, nil, guildID, "")
+	if err != nil {
+		guildLogger(guildID).Error("Couldn't get the premium status for rate limiting", "err", err)
+	}
+	if premium.IsExpired(prem, days) {
+		prem = premium.FreeTier
+	}
+	tier := strings.ToLower(premium.TierStrings[prem])
+	err = client.Set(ctx, guildTierCacheKey(guildID), tier, guildTierCacheTTL).Err()
+	if err != nil {
+		guildLogger(guildID).Error("Couldn't cache the premium tier", "err", err)
+	}
+	return tier
+}
+
+func (bot *Bot) rateLimitResponse(result redis_common.RateLimitResult, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	var content string
+	retry := fmt.Sprintf("<t:%d:R>", time.Now().Add(result.RetryAfter).Add(time.Second-1).Unix())
+	switch {
+	case result.Softbanned:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "softban.ignoring.until",
+			Other: "I'm ignoring you until {{.Until}}, stop spamming",
+		}, map[string]interface{}{
+			"Until": fmt.Sprintf("<t:%d:t>", time.Now().Add(bot.rateLimiter.SoftbanDuration()).Unix()),
+		})
+	case result.Guild:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "ratelimit.guild",
+			Other: "This server is using that too much right now; try again {{.Retry}}",
+		}, map[string]interface{}{
+			"Retry": retry,
+		})
+	default:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "softban.warning.retry",
+			Other: "Please stop spamming commands; you can use that again {{.Retry}}",
+		}, map[string]interface{}{
+			"Retry": retry,
+		})
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   1 << 6, //private message
+			Content: content,
+		},
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/capture"
+	"github.com/automuteus/automuteus/job"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/automuteus/metrics"
 	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/rediskey"
+	"github.com/automuteus/utils/pkg/task"
 	"github.com/bsm/redislock"
 	"github.com/bwmarrin/discordgo"
 	"github.com/go-redis/redis/v8"
-	"log"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -52,21 +58,21 @@ func (bot *Bot) refreshGameLiveness(code string) {
 }
 
 func (bot *Bot) rateLimitEventCallback(_ *discordgo.Session, rl *discordgo.RateLimit) {
-	log.Println(rl.Message)
+	logging.Default().Warn("Discord rate limited a request", "message", rl.Message)
 	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.InvalidRequest, 1)
 }
 
 func (redisInterface *RedisInterface) AddUniqueGuildCounter(guildID string) {
 	_, err := redisInterface.client.SAdd(ctx, rediskey.TotalGuildsSet, string(rediskey.HashGuildID(guildID))).Result()
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't add the guild to the guild counter", "err", err)
 	}
 }
 
 func (redisInterface *RedisInterface) LeaveUniqueGuildCounter(guildID string) {
 	_, err := redisInterface.client.SRem(ctx, rediskey.TotalGuildsSet, string(rediskey.HashGuildID(guildID))).Result()
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't remove the guild from the guild counter", "err", err)
 	}
 }
 
@@ -98,7 +104,7 @@ func (redisInterface *RedisInterface) LockVoiceChanges(connectCode string, dur t
 	if errors.Is(err, redislock.ErrNotObtained) {
 		return nil
 	} else if err != nil {
-		log.Println(err)
+		codeLogger(connectCode).Error("Couldn't lock the voice changes", "err", err)
 		return nil
 	}
 
@@ -112,7 +118,7 @@ func (redisInterface *RedisInterface) GetReadOnlyDiscordGameState(gsr GameStateR
 	for dgs == nil {
 		i++
 		if i > 10 {
-			log.Println("RETURNING NIL GAMESTATE FOR READONLY FETCH")
+			guildLogger(gsr.GuildID).Error("Returning a nil game state for a read-only fetch", "connectCode", gsr.ConnectCode, "channel", gsr.TextChannel)
 			return nil
 		}
 		dgs = redisInterface.getDiscordGameState(gsr)
@@ -140,7 +146,7 @@ func (redisInterface *RedisInterface) GetDiscordGameStateAndLock(gsr GameStateRe
 	if errors.Is(err, redislock.ErrNotObtained) {
 		return nil, nil
 	} else if err != nil {
-		log.Println(err)
+		gameLogger(gsr.GuildID, gsr.ConnectCode).Error("Couldn't lock the game state", "err", err)
 		return nil, nil
 	}
 
@@ -160,13 +166,13 @@ func (redisInterface *RedisInterface) getDiscordGameState(gsr GameStateRequest)
 		redisInterface.SetDiscordGameState(dgs, nil)
 		return dgs
 	case err != nil:
-		log.Println(err)
+		gameLogger(gsr.GuildID, gsr.ConnectCode).Error("Couldn't fetch the game state", "err", err)
 		return nil
 	default:
 		dgs := GameState{}
 		err := json.Unmarshal([]byte(jsonStr), &dgs)
 		if err != nil {
-			log.Println(err)
+			gameLogger(gsr.GuildID, gsr.ConnectCode).Error("Couldn't parse the game state", "err", err)
 			return nil
 		}
 		return &dgs
@@ -208,7 +214,7 @@ func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock
 
 	jBytes, err := json.Marshal(data)
 	if err != nil {
-		log.Println(err)
+		data.logger().Error("Couldn't serialize the game state", "err", err)
 		if lock != nil {
 			lock.Release(ctx)
 		}
@@ -217,7 +223,7 @@ func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock
 
 	err = redisInterface.client.Set(ctx, key, jBytes, GameTimeoutSeconds*time.Second).Err()
 	if err != nil {
-		log.Println(err)
+		data.logger().Error("Couldn't save the game state", "err", err)
 	}
 
 	if lock != nil {
@@ -227,21 +233,21 @@ func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock
 	if data.ConnectCode != "" {
 		err = redisInterface.client.Set(ctx, rediskey.ConnectCodePtr(data.GuildID, data.ConnectCode), key, GameTimeoutSeconds*time.Second).Err()
 		if err != nil {
-			log.Println(err)
+			data.logger().Error("Couldn't save the connect code pointer", "err", err)
 		}
 	}
 
 	if data.VoiceChannel != "" {
 		err = redisInterface.client.Set(ctx, rediskey.VoiceChannelPtr(data.GuildID, data.VoiceChannel), key, GameTimeoutSeconds*time.Second).Err()
 		if err != nil {
-			log.Println(err)
+			data.logger().Error("Couldn't save the voice channel pointer", "err", err)
 		}
 	}
 
 	if data.GameStateMsg.MessageChannelID != "" {
 		err = redisInterface.client.Set(ctx, rediskey.TextChannelPtr(data.GuildID, data.GameStateMsg.MessageChannelID), key, GameTimeoutSeconds*time.Second).Err()
 		if err != nil {
-			log.Println(err)
+			data.logger().Error("Couldn't save the text channel pointer", "err", err)
 		}
 	}
 }
@@ -255,7 +261,7 @@ func (redisInterface *RedisInterface) RefreshActiveGame(guildID, connectCode str
 	}).Result()
 
 	if err != nil {
-		log.Println(err)
+		gameLogger(guildID, connectCode).Error("Couldn't refresh the active game", "err", err)
 	}
 	before := t.Add(-time.Second * GameTimeoutSeconds)
 	go redisInterface.client.ZRemRangeByScore(context.Background(), rediskey.ActiveGamesZSet, "-inf", fmt.Sprintf("%d", before.Unix()))
@@ -266,7 +272,7 @@ func (redisInterface *RedisInterface) RemoveOldGame(guildID, connectCode string)
 
 	err := redisInterface.client.ZRem(ctx, key, connectCode).Err()
 	if err != nil {
-		log.Println(err)
+		gameLogger(guildID, connectCode).Error("Couldn't remove the old game", "err", err)
 	}
 }
 
@@ -284,7 +290,7 @@ func (redisInterface *RedisInterface) LoadAllActiveGames(guildID string) []strin
 	}).Result()
 
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't load the active games", "err", err)
 		return []string{}
 	}
 	go redisInterface.client.ZRemRangeByScore(context.Background(), hash, "-inf", fmt.Sprintf("%d", before))
@@ -296,7 +302,7 @@ func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	guildID := dgs.GuildID
 	connCode := dgs.ConnectCode
 	if guildID == "" || connCode == "" {
-		log.Println("Can't delete DGS with null guildID or null ConnCode")
+		dgs.logger().Warn("Can't delete a game state without a guild ID and connect code")
 	}
 	data := redisInterface.getDiscordGameState(GameStateRequest{
 		GuildID:     guildID,
@@ -311,9 +317,10 @@ func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	})
 	switch {
 	case errors.Is(err, redislock.ErrNotObtained):
-		fmt.Println("Could not obtain lock!")
+		dgs.logger().Warn("Couldn't obtain the lock to delete the game state")
 	case err != nil:
-		log.Fatalln(err)
+		dgs.logger().Error("Couldn't lock the game state to delete it", "err", err)
+		os.Exit(1)
 	default:
 		defer lock.Release(ctx)
 	}
@@ -321,20 +328,20 @@ func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	// delete all the pointers to the underlying -actual- discord data
 	err = redisInterface.client.Del(ctx, rediskey.TextChannelPtr(guildID, data.GameStateMsg.MessageChannelID)).Err()
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't delete the text channel pointer", "err", err)
 	}
 	err = redisInterface.client.Del(ctx, rediskey.VoiceChannelPtr(guildID, data.VoiceChannel)).Err()
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't delete the voice channel pointer", "err", err)
 	}
 	err = redisInterface.client.Del(ctx, rediskey.ConnectCodePtr(guildID, data.ConnectCode)).Err()
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't delete the connect code pointer", "err", err)
 	}
 
 	err = redisInterface.client.Del(ctx, key).Err()
 	if err != nil {
-		log.Println(err)
+		dgs.logger().Error("Couldn't delete the game state", "err", err)
 	}
 }
 
@@ -370,12 +377,12 @@ func (redisInterface *RedisInterface) DeleteLinksByUserID(guildID, userID string
 	// over all the usernames associated with just this userID, delete the underlying mapping of username->userID
 	usernames, err := redisInterface.GetUsernameOrUserIDMappings(guildID, userID)
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't fetch the user's usernames", "user", userID, "err", err)
 	} else {
 		for username := range usernames {
 			err := redisInterface.deleteHashSubEntry(guildID, username, userID)
 			if err != nil {
-				log.Println(err)
+				guildLogger(guildID).Error("Couldn't delete a username link", "user", userID, "err", err)
 			}
 		}
 	}
@@ -385,10 +392,39 @@ func (redisInterface *RedisInterface) DeleteLinksByUserID(guildID, userID string
 	return redisInterface.client.HDel(ctx, cacheHash, userID).Err()
 }
 
+// DeleteAllLinksByUserID deletes the user's links in every guild, like when they opt out
+func (redisInterface *RedisInterface) DeleteAllLinksByUserID(userID string) error {
+	prefix, suffix, _ := strings.Cut(rediskey.GuildCacheHash("*"), "*")
+	// there's no index from users to guilds, but opting out is rare enough to search for them
+	iter := redisInterface.client.Scan(ctx, 0, prefix+"*"+suffix, 0).Iterator()
+	for iter.Next(ctx) {
+		linked, err := redisInterface.client.HExists(ctx, iter.Val(), userID).Result()
+		if err != nil {
+			return err
+		}
+		if linked {
+			err = redisInterface.DeleteLinksByUserID(strings.TrimSuffix(strings.TrimPrefix(iter.Val(), prefix), suffix), userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return iter.Err()
+}
+
+// DeleteUsernameLink removes a single username<->userID mapping, leaving the user's other names alone
+func (redisInterface *RedisInterface) DeleteUsernameLink(guildID, userID, userName string) error {
+	err := redisInterface.deleteHashSubEntry(guildID, userID, userName)
+	if err != nil {
+		return err
+	}
+	return redisInterface.deleteHashSubEntry(guildID, userName, userID)
+}
+
 func (redisInterface *RedisInterface) appendToHashedEntry(guildID, key, value string) error {
 	resp, err := redisInterface.GetUsernameOrUserIDMappings(guildID, key)
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't fetch the username mappings", "err", err)
 	}
 
 	resp[value] = struct{}{}
@@ -399,7 +435,7 @@ func (redisInterface *RedisInterface) appendToHashedEntry(guildID, key, value st
 func (redisInterface *RedisInterface) deleteHashSubEntry(guildID, key, entry string) error {
 	entries, err := redisInterface.GetUsernameOrUserIDMappings(guildID, key)
 	if err != nil {
-		log.Println(err)
+		guildLogger(guildID).Error("Couldn't fetch the username mappings", "err", err)
 	} else {
 		delete(entries, entry)
 	}
@@ -424,13 +460,206 @@ func (redisInterface *RedisInterface) setUsernameOrUserIDMappings(guildID, key s
 	return err
 }
 
+// RecordJob adds a job to the game's recent history, and to its dead letters if it failed
+func (redisInterface *RedisInterface) RecordJob(connectCode string, record job.Record) {
+	jBytes, err := json.Marshal(record)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't serialize the job record", "err", err)
+		return
+	}
+	keys := []string{job.HistoryKey(connectCode)}
+	if record.Status == job.StatusFailed {
+		keys = append(keys, job.DeadLetterKey(connectCode))
+	}
+	pipe := redisInterface.client.Pipeline()
+	for _, key := range keys {
+		pipe.LPush(ctx, key, jBytes)
+		pipe.LTrim(ctx, key, 0, job.HistorySize-1)
+		pipe.Expire(ctx, key, time.Second*task.JobTTLSeconds)
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't record the job", "err", err)
+	}
+}
+
+func (redisInterface *RedisInterface) GetJobHistory(connectCode string) []job.Record {
+	return redisInterface.getJobRecords(job.HistoryKey(connectCode))
+}
+
+func (redisInterface *RedisInterface) GetDeadLetters(connectCode string) []job.Record {
+	return redisInterface.getJobRecords(job.DeadLetterKey(connectCode))
+}
+
+// getJobRecords returns the records in the list, most recent first
+func (redisInterface *RedisInterface) getJobRecords(key string) []job.Record {
+	strs, err := redisInterface.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		logging.Default().Error("Couldn't fetch the job records", "key", key, "err", err)
+		return []job.Record{}
+	}
+	records := make([]job.Record, 0, len(strs))
+	for _, str := range strs {
+		var record job.Record
+		err = json.Unmarshal([]byte(str), &record)
+		if err != nil {
+			logging.Default().Warn("Couldn't parse a job record", "key", key, "err", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// IsActiveConnectCode returns true if any game (on any shard) is currently using the connect code
+func (redisInterface *RedisInterface) IsActiveConnectCode(connectCode string) bool {
+	err := redisInterface.client.ZScore(ctx, rediskey.ActiveGamesZSet, connectCode).Err()
+	if errors.Is(err, redis.Nil) {
+		return false
+	} else if err != nil {
+		codeLogger(connectCode).Error("Couldn't check whether the connect code is active", "err", err)
+	}
+	// if we can't tell, assume it's taken
+	return true
+}
+
+func (redisInterface *RedisInterface) RemoveActiveConnectCode(connectCode string) {
+	err := redisInterface.client.ZRem(ctx, rediskey.ActiveGamesZSet, connectCode).Err()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't remove the active connect code", "err", err)
+	}
+}
+
+func (redisInterface *RedisInterface) SetCaptureLinkNonce(connectCode, nonce string, ttl time.Duration) error {
+	return redisInterface.client.Set(ctx, capture.LinkNonceKey(connectCode), nonce, ttl).Err()
+}
+
+// GetCaptureLinkNonce returns the nonce of the valid capture link for the connect code, or "" if it was revoked or expired
+func (redisInterface *RedisInterface) GetCaptureLinkNonce(connectCode string) string {
+	nonce, err := redisInterface.client.Get(ctx, capture.LinkNonceKey(connectCode)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			codeLogger(connectCode).Error("Couldn't fetch the capture link nonce", "err", err)
+		}
+		return ""
+	}
+	return nonce
+}
+
+// PersistCaptureLinkNonce keeps the capture link for the connect code valid until it's revoked
+func (redisInterface *RedisInterface) PersistCaptureLinkNonce(connectCode string) {
+	err := redisInterface.client.Persist(ctx, capture.LinkNonceKey(connectCode)).Err()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't persist the capture link nonce", "err", err)
+	}
+}
+
+// GetCaptureSession returns the session of the capture that connected with a valid link for the connect code, or an
+// empty session if none did
+func (redisInterface *RedisInterface) GetCaptureSession(connectCode string) capture.Session {
+	var session capture.Session
+	str, err := redisInterface.client.Get(ctx, capture.SessionKey(connectCode)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			codeLogger(connectCode).Error("Couldn't fetch the capture session", "err", err)
+		}
+		return session
+	}
+	err = json.Unmarshal([]byte(str), &session)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't parse the capture session", "err", err)
+	}
+	return session
+}
+
+// SetCaptureSession saves the session of the capture that connected, or deletes it if it's empty. Like the link's
+// nonce, it lasts until the link is revoked
+func (redisInterface *RedisInterface) SetCaptureSession(connectCode string, session capture.Session) {
+	var err error
+	if session.ID == "" {
+		err = redisInterface.client.Del(ctx, capture.SessionKey(connectCode)).Err()
+	} else {
+		var jBytes []byte
+		jBytes, err = json.Marshal(session)
+		if err == nil {
+			err = redisInterface.client.Set(ctx, capture.SessionKey(connectCode), jBytes, 0).Err()
+		}
+	}
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't save the capture session", "err", err)
+	}
+}
+
+func (redisInterface *RedisInterface) RevokeCaptureLink(connectCode string) {
+	err := redisInterface.client.Del(ctx, capture.LinkNonceKey(connectCode), capture.SessionKey(connectCode)).Err()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't revoke the capture link", "err", err)
+	}
+}
+
+func nameMatchHistoryKey(connectCode string) string {
+	return "automuteus:namematch:history:" + connectCode
+}
+
+func nameMatchPromptKey(connectCode, userID, playerName string) string {
+	return "automuteus:namematch:prompt:" + connectCode + ":" + userID + ":" + playerName
+}
+
+// RecordNameMatch adds a name matching decision to the game's recent history
+func (redisInterface *RedisInterface) RecordNameMatch(connectCode string, decision amongus.NameMatchDecision) {
+	jBytes, err := json.Marshal(decision)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't serialize the name match decision", "err", err)
+		return
+	}
+	key := nameMatchHistoryKey(connectCode)
+	pipe := redisInterface.client.Pipeline()
+	pipe.LPush(ctx, key, jBytes)
+	pipe.LTrim(ctx, key, 0, job.HistorySize-1)
+	pipe.Expire(ctx, key, GameTimeoutSeconds*time.Second)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't record the name match decision", "err", err)
+	}
+}
+
+// GetNameMatchHistory returns the game's recent name matching decisions, most recent first
+func (redisInterface *RedisInterface) GetNameMatchHistory(connectCode string) []amongus.NameMatchDecision {
+	strs, err := redisInterface.client.LRange(ctx, nameMatchHistoryKey(connectCode), 0, -1).Result()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't fetch the name match history", "err", err)
+		return []amongus.NameMatchDecision{}
+	}
+	decisions := make([]amongus.NameMatchDecision, 0, len(strs))
+	for _, str := range strs {
+		var decision amongus.NameMatchDecision
+		err = json.Unmarshal([]byte(str), &decision)
+		if err != nil {
+			codeLogger(connectCode).Warn("Couldn't parse a name match decision", "err", err)
+			continue
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// MarkNameMatchPrompted returns true if the user hasn't been asked about this player yet in this game
+func (redisInterface *RedisInterface) MarkNameMatchPrompted(connectCode, userID, playerName string) bool {
+	set, err := redisInterface.client.SetNX(ctx, nameMatchPromptKey(connectCode, userID, playerName), "", GameTimeoutSeconds*time.Second).Result()
+	if err != nil {
+		codeLogger(connectCode).Error("Couldn't mark the name match prompt", "err", err)
+		return false
+	}
+	return set
+}
+
 func (redisInterface *RedisInterface) LockSnowflake(snowflake string) *redislock.Lock {
 	locker := redislock.New(redisInterface.client)
 	lock, err := locker.Obtain(ctx, rediskey.SnowflakeLockID(snowflake), time.Millisecond*SnowflakeLockMs, nil)
 	if errors.Is(err, redislock.ErrNotObtained) {
 		return nil
 	} else if err != nil {
-		log.Println(err)
+		logging.Default().Error("Couldn't lock the snowflake", "snowflake", snowflake, "err", err)
 		return nil
 	}
 	return lock
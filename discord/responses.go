@@ -6,7 +6,6 @@ import (
 	"github.com/automuteus/automuteus/discord/command"
 	"github.com/automuteus/utils/pkg/discord"
 	"github.com/automuteus/utils/pkg/settings"
-	"os"
 	"strings"
 	"time"
 
@@ -243,6 +242,9 @@ func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSe
 
 	listResp := dgs.ToEmojiEmbedFields(emojis, sett)
 	listResp = append(gameInfoFields, listResp...)
+	if duplicates := dgs.GameData.DuplicateNames(); len(duplicates) > 0 {
+		listResp = append(listResp, duplicateNamesEmbedField(duplicates, sett))
+	}
 
 	color := 15158332 // red
 	desc := ""
@@ -287,6 +289,22 @@ func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSe
 	return &msg
 }
 
+func duplicateNamesEmbedField(names []string, sett *settings.GuildSettings) *discordgo.MessageEmbedField {
+	return &discordgo.MessageEmbedField{
+		Name: sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.lobbyMessage.DuplicateNames.Name",
+			Other: "⚠️ Duplicate names",
+		}),
+		Value: sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.lobbyMessage.DuplicateNames.Value",
+			Other: "More than one player is named `{{.Names}}`, so I can't link them automatically. Please select your color below",
+		}, map[string]interface{}{
+			"Names": strings.Join(names, "`, `"),
+		}),
+		Inline: false,
+	}
+}
+
 func gameOverMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSettings, winners string) *discordgo.MessageEmbed {
 	_, _, playMap := dgs.GameData.GetRoomRegionMap()
 
@@ -335,11 +353,14 @@ func gameOverMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.Guil
 	return &msg
 }
 
+// baseMapURL is where the map images are hosted; the default is used if it's empty
+var baseMapURL string
+
 func getThumbnailFromMap(playMap game.PlayMap, sett *settings.GuildSettings) *discordgo.MessageEmbedThumbnail {
 	var thumbNail *discordgo.MessageEmbedThumbnail = nil
 	if playMap != game.EMPTYMAP && playMap != game.DLEKS {
 		thumbNail = &discordgo.MessageEmbedThumbnail{
-			URL: command.FormMapUrl(os.Getenv("BASE_MAP_URL"), playMap, sett.MapVersion == "detailed"),
+			URL: command.FormMapUrl(baseMapURL, playMap, sett.MapVersion == "detailed"),
 		}
 	}
 	return thumbNail
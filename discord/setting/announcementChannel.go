@@ -0,0 +1,57 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnAnnouncementChannel(sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(AnnouncementChannel)
+	if sett == nil || ext == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		if ext.AnnouncementChannelID == "" {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingAnnouncementChannel.none",
+				Other: "No announcement channel",
+			}), s, sett), false
+		}
+		return ConstructEmbedForSetting(discord.MentionByChannelID(ext.AnnouncementChannelID), s, sett), false
+	}
+
+	if args[0] == Clear {
+		if ext.AnnouncementChannelID == "" {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingAnnouncementChannel.alreadyCleared",
+				Other: "There's no announcement channel; not doing anything",
+			}), false
+		}
+		ext.AnnouncementChannelID = ""
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingAnnouncementChannel.cleared",
+			Other: "From now on, I won't post announcements about AutoMuteUs",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromText(args[0])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingAnnouncementChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid text channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[0],
+			}), false
+	}
+	ext.AnnouncementChannelID = channelID
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingAnnouncementChannel.withChannelID",
+		Other: "From now on, I'll post announcements about AutoMuteUs in {{.channelID}}",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
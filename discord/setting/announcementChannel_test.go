@@ -0,0 +1,48 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+)
+
+func TestFnAnnouncementChannel(t *testing.T) {
+	_, valid := FnAnnouncementChannel(nil, nil, []string{})
+	if valid {
+		t.Error("Sending nil settings should never result in valid settings change")
+	}
+
+	sett := settings.MakeGuildSettings()
+	ext := storage.MakeExtendedGuildSettings()
+	_, valid = FnAnnouncementChannel(sett, ext, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	_, valid = FnAnnouncementChannel(sett, ext, []string{Clear})
+	if valid {
+		t.Error("Clearing an empty channel shouldn't result in valid settings change")
+	}
+
+	_, valid = FnAnnouncementChannel(sett, ext, []string{"somegarbage"})
+	if valid {
+		t.Error("Garbage channel shouldn't result in valid settings change")
+	}
+
+	_, valid = FnAnnouncementChannel(sett, ext, []string{"<#888888066283941888>"})
+	if !valid {
+		t.Error("Channel mention should result in valid settings change")
+	}
+	if ext.AnnouncementChannelID != "888888066283941888" {
+		t.Errorf("Expected the announcement channel to be set, got %s", ext.AnnouncementChannelID)
+	}
+
+	_, valid = FnAnnouncementChannel(sett, ext, []string{Clear})
+	if !valid {
+		t.Error("Clearing the channel should result in valid settings change")
+	}
+	if ext.AnnouncementChannelID != "" {
+		t.Error("Expected the announcement channel to be cleared")
+	}
+}
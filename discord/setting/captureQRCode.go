@@ -0,0 +1,52 @@
+package setting
+
+import (
+	"fmt"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnCaptureQRCode(sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(CaptureQRCode)
+	if sett == nil || ext == nil {
+		return nil, false
+	}
+	if len(args) == 0 {
+		return ConstructEmbedForSetting(fmt.Sprintf("%v", ext.CaptureQRCode), s, sett), false
+	}
+
+	val := args[0]
+	if val != "true" && val != "false" {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureQRCode.Unrecognized",
+			Other: "{{.Arg}} is not a true/false value. See `/settings capture-qr-code` for usage",
+		},
+			map[string]interface{}{
+				"Arg": val,
+			}), false
+	}
+
+	newSet := val == "true"
+	if ext.CaptureQRCode == newSet {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureQRCode.Noop",
+			Other: "Capture QR Code was already set to `{{.Value}}`; not doing anything",
+		},
+			map[string]interface{}{
+				"Value": newSet,
+			}), false
+	}
+	ext.CaptureQRCode = newSet
+	if newSet {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureQRCode.True",
+			Other: "From now on, I'll include a QR code of the capture link when starting a new game",
+		}), true
+	} else {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureQRCode.False",
+			Other: "From now on, I won't include a QR code of the capture link when starting a new game",
+		}), true
+	}
+}
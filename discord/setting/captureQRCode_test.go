@@ -0,0 +1,39 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+	"testing"
+)
+
+func TestFnCaptureQRCode(t *testing.T) {
+	_, valid := FnCaptureQRCode(nil, nil, []string{})
+	if valid {
+		t.Error("Sending nil settings should never result in valid settings change")
+	}
+
+	sett := settings.MakeGuildSettings()
+	ext := storage.MakeExtendedGuildSettings()
+	_, valid = FnCaptureQRCode(sett, ext, []string{})
+	if valid {
+		t.Error("Sending no args should never result in valid settings change")
+	}
+
+	_, valid = FnCaptureQRCode(sett, ext, []string{"nontrue"})
+	if valid {
+		t.Error("Sending invalid (non true/false) val should never result in valid settings change")
+	}
+
+	_, valid = FnCaptureQRCode(sett, ext, []string{"true"})
+	if valid {
+		t.Error("Sending old val should never result in valid settings change")
+	}
+
+	_, valid = FnCaptureQRCode(sett, ext, []string{"false"})
+	if !valid {
+		t.Error("Sending new false val should result in valid settings change")
+	}
+	if ext.CaptureQRCode {
+		t.Error("Capture QR Code setting was not set false correctly")
+	}
+}
@@ -0,0 +1,141 @@
+package setting
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnCommandPermissions views or changes who may use which commands. The role and user subcommands toggle: using them
+// again for the same command removes the role or user. keys are the commands and subcommands that can be configured
+func FnCommandPermissions(sett *settings.GuildSettings, ext *storage.ExtendedGuildSettings, keys []string, args []string) (interface{}, bool) {
+	s := GetSettingByName(CommandPermissions)
+	if sett == nil || ext == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		if len(ext.CommandPermissions) == 0 {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingCommandPermissions.default",
+				Other: "Every command uses its default permissions",
+			}), s, sett), false
+		}
+		configured := make([]string, 0, len(ext.CommandPermissions))
+		for key := range ext.CommandPermissions {
+			configured = append(configured, key)
+		}
+		sort.Strings(configured)
+		lines := make([]string, len(configured))
+		for i, key := range configured {
+			lines[i] = "`/" + key + "`: " + strings.Join(ext.CommandPermissions[key].Mentions(), ", ")
+		}
+		return ConstructEmbedForSetting(strings.Join(lines, "\n"), s, sett), false
+	}
+
+	if args[0] == Clear {
+		if len(ext.CommandPermissions) == 0 {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingCommandPermissions.alreadyDefault",
+				Other: "Every command already uses its default permissions; not doing anything",
+			}), false
+		}
+		ext.CommandPermissions = nil
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCommandPermissions.clear",
+			Other: "From now on, every command uses its default permissions",
+		}), true
+	}
+
+	if len(args) != 3 || (args[0] != Role && args[0] != User) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCommandPermissions.Unrecognized",
+			Other: "Sorry, I didn't understand that. See `/settings permissions` for usage",
+		}), false
+	}
+
+	key := strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(args[1]), "/"))), " ")
+	if !contains(keys, key) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCommandPermissions.unknownCommand",
+			Other: "Sorry, I don't know the command `{{.Command}}`",
+		},
+			map[string]interface{}{
+				"Command": args[1],
+			}), false
+	}
+
+	var ID, mention string
+	var err error
+	if args[0] == Role {
+		ID, err = discord.ExtractRoleIDFromText(args[2])
+		mention = "<@&" + ID + ">"
+	} else {
+		ID, err = discord.ExtractUserIDFromText(args[2])
+		mention = discord.MentionByUserID(ID)
+	}
+	if ID == "" || err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCommandPermissions.notFound",
+			Other: "Sorry, I didn't recognize the role or user you provided",
+		}), false
+	}
+
+	if ext.CommandPermissions == nil {
+		ext.CommandPermissions = map[string]*storage.CommandPermission{}
+	}
+	perm, ok := ext.CommandPermissions[key]
+	if !ok {
+		perm = &storage.CommandPermission{}
+		ext.CommandPermissions[key] = perm
+	}
+	IDs := &perm.UserIDs
+	if args[0] == Role {
+		IDs = &perm.RoleIDs
+	}
+
+	if contains(*IDs, ID) {
+		*IDs = remove(*IDs, ID)
+		if len(perm.RoleIDs) == 0 && len(perm.UserIDs) == 0 {
+			delete(ext.CommandPermissions, key)
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingCommandPermissions.removedLast",
+				Other: "{{.Mention}} was the last one allowed to use `/{{.Command}}`, so it uses its default permissions again",
+			},
+				map[string]interface{}{
+					"Mention": mention,
+					"Command": key,
+				}), true
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCommandPermissions.removed",
+			Other: "{{.Mention}} can no longer use `/{{.Command}}`",
+		},
+			map[string]interface{}{
+				"Mention": mention,
+				"Command": key,
+			}), true
+	}
+	*IDs = append(*IDs, ID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingCommandPermissions.added",
+		Other: "{{.Mention}} can now use `/{{.Command}}`. Only admins and the roles and users you allow can use it",
+	},
+		map[string]interface{}{
+			"Mention": mention,
+			"Command": key,
+		}), true
+}
+
+func remove(arr []string, elem string) []string {
+	removed := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if v != elem {
+			removed = append(removed, v)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,73 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/settings"
+)
+
+func TestFnCommandPermissions(t *testing.T) {
+	_, valid := FnCommandPermissions(nil, nil, nil, []string{})
+	if valid {
+		t.Error("Sending nil settings should never result in valid settings change")
+	}
+
+	sett := settings.MakeGuildSettings()
+	ext := storage.MakeExtendedGuildSettings()
+	keys := []string{"new", "pause", "stats", "stats clear"}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Clear})
+	if valid {
+		t.Error("Clearing the default permissions shouldn't result in valid settings change")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Role, "nonexistent", "<@&888888066283941888>"})
+	if valid {
+		t.Error("Unknown commands shouldn't result in valid settings change")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Role, "new", "somegarbage"})
+	if valid {
+		t.Error("Garbage role shouldn't result in valid settings change")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Role, "/New", "<@&888888066283941888>"})
+	if !valid {
+		t.Error("Allowing a role to use a command should result in valid settings change")
+	}
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{User, " stats  clear", "<@!888888066283941999>"})
+	if !valid {
+		t.Error("Allowing a user to use a subcommand should result in valid settings change")
+	}
+	if perm := ext.CommandPermissions["new"]; perm == nil || len(perm.RoleIDs) != 1 || perm.RoleIDs[0] != "888888066283941888" {
+		t.Errorf("Expected the role to be allowed to use new, got %v", ext.CommandPermissions)
+	}
+	if perm := ext.CommandPermissions["stats clear"]; perm == nil || len(perm.UserIDs) != 1 || perm.UserIDs[0] != "888888066283941999" {
+		t.Errorf("Expected the user to be allowed to use stats clear, got %v", ext.CommandPermissions)
+	}
+	if !ext.CommandPermissions["new"].Allows("1", []string{"888888066283941888"}) || ext.CommandPermissions["new"].Allows("888888066283941999", nil) {
+		t.Error("Expected only members with the role to be allowed to use new")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Role, "new", "<@&888888066283941888>"})
+	if !valid {
+		t.Error("Disallowing a role should result in valid settings change")
+	}
+	if _, ok := ext.CommandPermissions["new"]; ok {
+		t.Error("Expected new to use its default permissions after disallowing the only role")
+	}
+
+	_, valid = FnCommandPermissions(sett, ext, keys, []string{Clear})
+	if !valid {
+		t.Error("Clearing the permissions should result in valid settings change")
+	}
+	if len(ext.CommandPermissions) != 0 {
+		t.Error("Expected no permissions after clearing")
+	}
+}
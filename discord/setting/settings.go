@@ -22,6 +22,8 @@ const (
 	Clear = "clear"
 	User  = "user"
 	Role  = "role"
+
+	Command = "command"
 )
 
 var (
@@ -50,6 +52,9 @@ const (
 	LeaderboardMin      = "leaderboard-min"
 	MuteSpectators      = "mute-spectators"
 	DisplayRoomCode     = "display-room-code"
+	CaptureQRCode       = "capture-qr-code"
+	CommandPermissions  = "permissions"
+	AnnouncementChannel = "announcement-channel"
 	Show                = "show"
 	List                = "list"
 	Reset               = "reset"
@@ -407,6 +412,106 @@ var AllSettings = []Setting{
 		},
 		Premium: true,
 	},
+	{
+		Name:      CaptureQRCode,
+		ShortDesc: "QR Code for the capture link",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "enabled",
+				Description: "enabled",
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      CommandPermissions,
+		ShortDesc: "Who can use each command",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        View,
+				Description: "View who can use each command",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Clear,
+				Description: "Use the default permissions for all commands",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        Role,
+				Description: "Allow or disallow a role to use a command",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:         Command,
+						Description:  "Command or subcommand, like \"new\" or \"stats clear\"",
+						Type:         discordgo.ApplicationCommandOptionString,
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Name:        Role,
+						Description: "Discord role to allow or disallow",
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        User,
+				Description: "Allow or disallow a user to use a command",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:         Command,
+						Description:  "Command or subcommand, like \"new\" or \"stats clear\"",
+						Type:         discordgo.ApplicationCommandOptionString,
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Name:        User,
+						Description: "Discord user to allow or disallow",
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Required:    true,
+					},
+				},
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      AnnouncementChannel,
+		ShortDesc: "Channel for announcements about AutoMuteUs",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        View,
+				Description: "View the announcement channel",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Clear,
+				Description: "Don't post announcements",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "channel",
+				Description: "Channel to post announcements in",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:         "channel",
+						Description:  "Channel to post announcements in",
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						Required:     true,
+					},
+				},
+			},
+		},
+		Premium: false,
+	},
 	{
 		Name:      Show,
 		ShortDesc: "Show All Current Settings",
@@ -3,7 +3,9 @@ package discord
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/automuteus/automuteus/discord/command"
 	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/settings"
 	"log"
 )
@@ -68,8 +70,44 @@ func (bot *Bot) HandleSettingsCommand(guildID string, sett *settings.GuildSettin
 			return nonPremiumSettingResponse(sett)
 		}
 		sendMsg, isValid = setting.FnDisplayRoomCode(sett, args)
+	case setting.CaptureQRCode:
+		ext := bot.StorageInterface.GetExtendedGuildSettings(guildID)
+		sendMsg, isValid = setting.FnCaptureQRCode(sett, ext, args)
+		if isValid {
+			err := bot.StorageInterface.SetExtendedGuildSettings(guildID, ext)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		// the regular guild settings weren't changed
+		return sendMsg
+	case setting.CommandPermissions:
+		ext := bot.StorageInterface.GetExtendedGuildSettings(guildID)
+		sendMsg, isValid = setting.FnCommandPermissions(sett, ext, command.PermissionKeys(), args)
+		if isValid {
+			err := bot.StorageInterface.SetExtendedGuildSettings(guildID, ext)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		// the regular guild settings weren't changed
+		return sendMsg
+	case setting.AnnouncementChannel:
+		ext := bot.StorageInterface.GetExtendedGuildSettings(guildID)
+		sendMsg, isValid = setting.FnAnnouncementChannel(sett, ext, args)
+		if isValid {
+			err := bot.StorageInterface.SetExtendedGuildSettings(guildID, ext)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		// the regular guild settings weren't changed
+		return sendMsg
 	case setting.Show:
-		jBytes, err := json.MarshalIndent(sett, "", "  ")
+		jBytes, err := json.MarshalIndent(struct {
+			*settings.GuildSettings
+			*storage.ExtendedGuildSettings
+		}{sett, bot.StorageInterface.GetExtendedGuildSettings(guildID)}, "", "  ")
 		if err != nil {
 			log.Println(err)
 			return err
@@ -78,6 +116,10 @@ func (bot *Bot) HandleSettingsCommand(guildID string, sett *settings.GuildSettin
 		return fmt.Sprintf("```JSON\n%s\n```", jBytes)
 	case setting.Reset:
 		sett = settings.MakeGuildSettings()
+		err := bot.StorageInterface.DeleteExtendedGuildSettings(guildID)
+		if err != nil {
+			log.Println(err)
+		}
 		sendMsg = "Resetting guild settings to default values"
 		isValid = true
 	case setting.List:
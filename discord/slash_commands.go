@@ -3,7 +3,6 @@ package discord
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"regexp"
 	"strings"
 	"time"
@@ -12,7 +11,9 @@ import (
 	"github.com/automuteus/automuteus/discord/command"
 	"github.com/automuteus/automuteus/discord/setting"
 	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/premium"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/bwmarrin/discordgo"
@@ -38,72 +39,101 @@ const (
 	resetGuildCanceledID  = "reset-guild-canceled"
 )
 
-func (bot *Bot) handleInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	respondChan := make(chan *discordgo.InteractionResponse)
-	ticker := time.NewTicker(time.Second * 2)
-	var followUpMsg *discordgo.Message
-	var err error
+// isSlowComponent is whether the component locks the game state, mutes through Galactus or writes to Postgres, any of
+// which can take longer than the 3 seconds Discord gives us to respond
+func isSlowComponent(customID string) bool {
+	switch customID {
+	case gamePauseID, gameRefreshID, gameUnmuteAllConfirmedID, gameEndConfirmedID, colorSelectID,
+		resetUserConfirmedID, resetGuildConfirmedID:
+		return true
+	}
+	return strings.HasPrefix(customID, nameMatchIDPrefix) || strings.HasPrefix(customID, linkUserIDPrefix)
+}
 
-	// get the result in the background
-	go func() {
-		respondChan <- bot.slashCommandHandler(s, i)
+func (bot *Bot) handleInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	start := time.Now()
+	interactionType, name := metrics.ComponentInteraction, metrics.ComponentInteraction
+	// update is whether a deferred component's message is updated, rather than a deferred command responded to
+	private, deferred, update := false, false, false
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		interactionType, name = metrics.CommandInteraction, i.ApplicationCommandData().Name
+		private, deferred = command.Deferred[name]
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		interactionType, name = metrics.AutocompleteInteraction, i.ApplicationCommandData().Name
+	case discordgo.InteractionMessageComponent:
+		deferred = isSlowComponent(i.MessageComponentData().CustomID)
+		update = deferred
+	}
+	defer func() {
+		metrics.RecordInteractionLatency(interactionType, name, deferred, time.Since(start))
 	}()
 
-	for {
-		select {
-		case <-ticker.C:
-			// only followup the first time
-			if followUpMsg == nil {
-				err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Flags:   1 << 6,
-						Content: "Give me just a little bit to make a proper response :)",
-					},
-				})
-				if err != nil {
-					log.Println("err issuing wait response ", err)
-				}
-				followUpMsg, err = s.FollowupMessageCreate(s.State.User.ID, i.Interaction, true, &discordgo.WebhookParams{
-					Content: Hourglass,
-				})
-				if err != nil {
-					log.Println("Error creating followup message: ", err)
-				}
-			}
-			// don't return here
+	if deferred {
+		deferral := &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{},
+		}
+		if private {
+			deferral.Data.Flags = 1 << 6
+		}
+		if update {
+			// the message stays as it is until it's edited or there's a follow-up
+			deferral = &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+		}
+		err := s.InteractionRespond(i.Interaction, deferral)
+		if err != nil {
+			guildLogger(i.GuildID).Error("Couldn't defer the interaction response", "err", err)
+			return
+		}
+	}
 
-		case resp := <-respondChan:
-			if followUpMsg != nil {
-				if resp != nil && resp.Data != nil {
-					content := resp.Data.Content
-					if content == "" {
-						content = "\u200b"
-					}
-					followUpMsg, err = s.FollowupMessageEdit(s.State.User.ID, i.Interaction, followUpMsg.ID, &discordgo.WebhookEdit{
-						Content:    content,
-						Components: resp.Data.Components,
-						Embeds:     resp.Data.Embeds,
-					})
-				} else {
-					//TODO if this shows up in logs regularly, print more context
-					log.Println("received a nil response, or resp.data was nil")
-				}
-			} else if resp != nil {
-				err = s.InteractionRespond(i.Interaction, resp)
-				if err != nil {
-					log.Println("error issuing interaction response: ", err)
-					iBytes, err := json.Marshal(i.Interaction)
-					if err != nil {
-						log.Println(err)
-					} else {
-						log.Println(string(iBytes))
-					}
-				}
+	resp := bot.slashCommandHandler(s, i)
+	if resp == nil {
+		if deferred && !update {
+			// don't leave the "thinking…" placeholder up forever
+			err := s.InteractionResponseDelete(i.Interaction)
+			if err != nil {
+				guildLogger(i.GuildID).Error("Couldn't delete the deferred interaction response", "err", err)
 			}
-			ticker.Stop()
-			// no matter what we get back, return
-			return
+		}
+		return
+	}
+	// autocompletes must be answered with choices, never with a message
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete && resp.Type != discordgo.InteractionApplicationCommandAutocompleteResult {
+		return
+	}
+
+	var err error
+	switch {
+	case !deferred:
+		err = s.InteractionRespond(i.Interaction, resp)
+	case resp.Data == nil:
+		guildLogger(i.GuildID).Warn("Received a response without data for a deferred command", "interaction", i.ID, "channel", i.ChannelID)
+		return
+	case update && resp.Type == discordgo.InteractionResponseUpdateMessage:
+		_, err = s.InteractionResponseEdit(i.Interaction, command.DeferredEdit(resp))
+	case update:
+		// a new message in response to a component
+		_, err = s.FollowupMessageCreate(i.Interaction, false, command.DeferredFollowup(resp))
+	case command.IsPrivate(resp) == private:
+		_, err = s.InteractionResponseEdit(i.Interaction, command.DeferredEdit(resp))
+	default:
+		// the deferred response can't change whether it's private, so replace it with a follow-up that is (or isn't)
+		err = s.InteractionResponseDelete(i.Interaction)
+		if err != nil {
+			guildLogger(i.GuildID).Error("Couldn't delete the deferred interaction response", "err", err)
+		}
+		_, err = s.FollowupMessageCreate(i.Interaction, false, command.DeferredFollowup(resp))
+	}
+	if err != nil {
+		logger := guildLogger(i.GuildID)
+		logger.Error("Couldn't issue the interaction response", "err", err)
+		iBytes, err := json.Marshal(i.Interaction)
+		if err != nil {
+			logger.Error("Couldn't serialize the interaction", "err", err)
+		} else {
+			logger.Debug("The interaction that couldn't be responded to", "interaction", string(iBytes))
 		}
 	}
 }
@@ -125,27 +155,23 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 	defer interactionLock.Release(ctx)
 
 	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+	// for ephemeral responses only
+	userSett := userSettings(sett, i.Locale)
 
-	// TODO respond properly for commands that *can* be performed in DMs. Such as minimal stats queries, help, info, etc
 	// NOTE: difference between i.Member.User (Server/Guild chat) vs i.User (DMs)
 	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
-		return command.DmResponse(sett)
-	}
-
-	if redis_common.IsUserRateLimitedGeneral(bot.RedisInterface.client, i.Member.User.ID) {
-		banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, i.Member.User.ID)
-		return softbanResponse(banned, sett)
+		return bot.dmCommandHandler(s, i, userSett)
 	}
 
 	g, err := s.State.Guild(i.GuildID)
 	if err != nil {
-		log.Println(err)
-		return command.PrivateErrorResponse("get-guild", err, sett)
+		guildLogger(i.GuildID).Error("Couldn't get the guild from the state", "err", err)
+		return command.PrivateErrorResponse("get-guild", err, userSett)
 	}
 	perm, err := bot.PrimarySession.State.UserChannelPermissions(s.State.User.ID, i.ChannelID)
 	if err != nil {
-		log.Println(err)
-		return command.PrivateErrorResponse("get-permissions", err, sett)
+		guildLogger(i.GuildID).Error("Couldn't get the bot's channel permissions", "channel", i.ChannelID, "err", err)
+		return command.PrivateErrorResponse("get-permissions", err, userSett)
 	}
 	missingPerms := checkPermissions(perm, RequiredPermissions)
 	if missingPerms > 0 {
@@ -176,37 +202,37 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 		TextChannel: i.ChannelID,
 	}
 
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return bot.handleAutocomplete(i, gsr, userSett)
+	}
+
 	if i.Type == discordgo.InteractionApplicationCommand {
-		if redis_common.IsUserRateLimitedSpecific(bot.RedisInterface.client, i.Member.User.ID, i.ApplicationCommandData().Name) {
-			banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, i.Member.User.ID)
-			return softbanResponse(banned, sett)
+		if result := bot.rateLimit(i.Member.User.ID, i.GuildID, i.ApplicationCommandData().Name); !result.Allowed {
+			return bot.rateLimitResponse(result, userSett)
 		}
-		var cmdRatelimitTimeout = redis_common.GlobalUserRateLimitDuration
-		// /new has a longer ratelimit window than other commands (it's an expensive operation)
-		if i.ApplicationCommandData().Name == command.New.Name {
-			cmdRatelimitTimeout = redis_common.NewGameRateLimitDuration
+
+		ext := bot.StorageInterface.GetExtendedGuildSettings(i.GuildID)
+		permitted, granted := commandPermitted(command.PermissionKey(i.ApplicationCommandData()), i.Member, ext, isAdmin, isPermissioned)
+		if !permitted {
+			return command.InsufficientPermissionsResponse(userSett)
 		}
-		redis_common.MarkUserRateLimit(bot.RedisInterface.client, i.Member.User.ID, i.ApplicationCommandData().Name, cmdRatelimitTimeout)
 		switch i.ApplicationCommandData().Name {
 		case command.Help.Name:
-			return command.HelpResponse(sett, i.ApplicationCommandData().Options)
+			return command.HelpResponse(userSett, bot.StorageInterface.GetExtendedGuildSettings(i.GuildID), i.ApplicationCommandData().Options)
 
 		case command.Info.Name:
 			botInfo := bot.getInfo()
 			return command.InfoResponse(botInfo, i.GuildID, sett)
 
 		case command.Link.Name:
-			if !isPermissioned {
-				return command.InsufficientPermissionsResponse(sett)
-			}
 			userID, color := command.GetLinkParams(s, i.ApplicationCommandData().Options)
 
 			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
 			if lock == nil {
-				log.Printf("No lock could be obtained when linking for guild %s, channel %s\n", i.GuildID, i.ChannelID)
-				return command.DeadlockGameStateResponse(command.Link.Name, sett)
+				guildLogger(i.GuildID).Warn("No lock could be obtained when linking", "channel", i.ChannelID)
+				return command.DeadlockGameStateResponse(command.Link.Name, userSett)
 			}
-			resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, color, sett)
+			resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, color, userSett)
 			if success {
 				bot.RedisInterface.SetDiscordGameState(dgs, lock)
 				bot.DispatchRefreshOrEdit(dgs, gsr, sett)
@@ -217,17 +243,14 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			return resp
 
 		case command.Unlink.Name:
-			if !isPermissioned {
-				return command.InsufficientPermissionsResponse(sett)
-			}
 			userID := command.GetUnlinkParams(s, i.ApplicationCommandData().Options)
 
 			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
 			if lock == nil {
-				log.Printf("No lock could be obtained when unlinking for guild %s, channel %s\n", i.GuildID, i.ChannelID)
-				return command.DeadlockGameStateResponse(command.Unlink.Name, sett)
+				guildLogger(i.GuildID).Warn("No lock could be obtained when unlinking", "channel", i.ChannelID)
+				return command.DeadlockGameStateResponse(command.Unlink.Name, userSett)
 			}
-			resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, "", sett)
+			resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, "", userSett)
 			if success {
 				bot.RedisInterface.SetDiscordGameState(dgs, lock)
 				bot.DispatchRefreshOrEdit(dgs, gsr, sett)
@@ -237,26 +260,71 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			}
 			return resp
 
-		case command.Settings.Name:
-			if !isAdmin {
-				return command.InsufficientPermissionsResponse(sett)
+		case command.LinkUser.Name:
+			userID := i.ApplicationCommandData().TargetID
+			dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+			if dgs == nil {
+				return command.DeadlockGameStateResponse(command.LinkUser.Name, userSett)
+			}
+			if !dgs.GameStateMsg.Exists() {
+				return command.NoGameResponse(userSett)
+			}
+			return command.LinkUserResponse(userID, linkUserCustomID(userID), dgs.playerSelectMenuOptions(GlobalAlivenessEmojis[true]), userSett)
+
+		case command.UnlinkUser.Name:
+			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
+			if lock == nil {
+				guildLogger(i.GuildID).Warn("No lock could be obtained when unlinking", "channel", i.ChannelID)
+				return command.DeadlockGameStateResponse(command.UnlinkUser.Name, userSett)
+			}
+			resp, success := bot.linkOrUnlinkAndRespond(dgs, i.ApplicationCommandData().TargetID, "", userSett)
+			if success {
+				bot.RedisInterface.SetDiscordGameState(dgs, lock)
+				bot.DispatchRefreshOrEdit(dgs, gsr, sett)
+			} else {
+				// release the lock
+				bot.RedisInterface.SetDiscordGameState(nil, lock)
 			}
+			return resp
+
+		case command.Settings.Name:
 			premStatus, days, err := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, bot.TopGGClient, i.GuildID, i.Member.User.ID)
 			if err != nil {
-				log.Println("Err in /settings get premium:", err)
+				guildLogger(i.GuildID).Error("Couldn't get the premium status for /settings", "err", err)
 			}
 			setting, args := command.GetSettingsParams(i.ApplicationCommandData().Options)
 			msg := bot.HandleSettingsCommand(i.GuildID, sett, setting, args, !premium.IsExpired(premStatus, days))
 			return command.SettingsResponse(msg)
 
 		case command.New.Name:
-			if !isPermissioned {
-				return command.InsufficientPermissionsResponse(sett)
+			if command.GetNewParams(i.ApplicationCommandData().Options) {
+				dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+				if dgs == nil {
+					return command.DeadlockGameStateResponse(command.New.Name, userSett)
+				}
+				if !dgs.GameStateMsg.Exists() || dgs.ConnectCode == "" {
+					return command.NewResponse(command.NewNoGame, command.NewInfo{}, userSett)
+				}
+				hyperlink, minimalURL := bot.issueCaptureURL(dgs.ConnectCode)
+				info := command.NewInfo{
+					Hyperlink:   hyperlink,
+					MinimalURL:  minimalURL,
+					ConnectCode: dgs.ConnectCode,
+				}
+				if bot.StorageInterface.GetExtendedGuildSettings(i.GuildID).CaptureQRCode {
+					info.QRCode = captureQRCode(hyperlink)
+				}
+				return command.NewResponse(command.NewSuccess, info, userSett)
+			}
+
+			// running games keep going during maintenance, and their capture links can still be regenerated
+			if maintenance, eta := redis_common.GetMaintenance(bot.RedisInterface.client); maintenance {
+				return command.NewResponse(command.NewMaintenance, command.NewInfo{MaintenanceETA: eta}, userSett)
 			}
 
 			voiceChannelID := getTrackingChannel(g, i.Member.User.ID)
 			if voiceChannelID == "" {
-				return command.NewResponse(command.NewNoVoiceChannel, command.NewInfo{}, sett)
+				return command.NewResponse(command.NewNoVoiceChannel, command.NewInfo{}, userSett)
 			}
 
 			perm, err = bot.PrimarySession.State.UserChannelPermissions(s.State.User.ID, voiceChannelID)
@@ -267,11 +335,15 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 
 			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
 			if lock == nil {
-				log.Printf("No lock could be obtained when making a new game for guild %s, channel %s\n", i.GuildID, i.ChannelID)
-				return command.DeadlockGameStateResponse(command.New.Name, sett)
+				guildLogger(i.GuildID).Warn("No lock could be obtained when making a new game", "channel", i.ChannelID)
+				return command.DeadlockGameStateResponse(command.New.Name, userSett)
 			}
 
-			status, activeGames := bot.newGame(dgs)
+			personalCode, err := storage.GetPersonalConnectCode(bot.PostgresInterface, i.Member.User.ID)
+			if err != nil {
+				guildLogger(i.GuildID).Error("Couldn't get the personal connect code", "user", i.Member.User.ID, "err", err)
+			}
+			status, activeGames := bot.newGame(dgs, personalCode)
 			if status == command.NewSuccess {
 				// release the lock
 				bot.RedisInterface.SetDiscordGameState(dgs, lock)
@@ -286,16 +358,26 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 				bot.EndGameChannels[dgs.ConnectCode] = killChan
 				bot.ChannelsMapLock.Unlock()
 
-				hyperlink, minimalURL := formCaptureURL(bot.url, dgs.ConnectCode)
+				var hyperlink, minimalURL string
+				if dgs.ConnectCode == personalCode {
+					// personal links are long-lived; they're revoked with /personal-link instead of expiring
+					hyperlink, minimalURL = formCaptureURL(bot.url, dgs.ConnectCode, "")
+				} else {
+					hyperlink, minimalURL = bot.issueCaptureURL(dgs.ConnectCode)
+				}
 
 				bot.handleGameStartMessage(i.GuildID, i.ChannelID, voiceChannelID, i.Member.User.ID, sett, g, dgs.ConnectCode)
 
-				return command.NewResponse(status, command.NewInfo{
+				info := command.NewInfo{
 					Hyperlink:   hyperlink,
 					MinimalURL:  minimalURL,
 					ConnectCode: dgs.ConnectCode,
 					ActiveGames: activeGames, // not actually needed for Success messages
-				}, sett)
+				}
+				if bot.StorageInterface.GetExtendedGuildSettings(i.GuildID).CaptureQRCode {
+					info.QRCode = captureQRCode(hyperlink)
+				}
+				return command.NewResponse(status, info, userSett)
 			} else {
 				// release the lock
 				bot.RedisInterface.SetDiscordGameState(nil, lock)
@@ -307,91 +389,124 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			if bot.RefreshGameStateMessage(gsr, sett) {
 				return command.PrivateResponse(ThumbsUp)
 			} else {
-				return command.NoGameResponse(sett)
+				return command.NoGameResponse(userSett)
 			}
 
 		case command.Pause.Name:
-			if !isPermissioned {
-				return command.InsufficientPermissionsResponse(sett)
-			}
-			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
-			if lock == nil {
-				log.Printf("No lock could be obtained when pausing game for guild %s, channel %s\n", i.GuildID, i.ChannelID)
-				return command.DeadlockGameStateResponse(command.Pause.Name, sett)
-			}
-			if !dgs.GameStateMsg.Exists() {
-				bot.RedisInterface.SetDiscordGameState(nil, lock)
-				return command.NoGameResponse(sett)
-			}
+			return bot.pauseGame(gsr, sett, userSett)
 
-			dgs.Running = !dgs.Running
-
-			bot.RedisInterface.SetDiscordGameState(dgs, lock)
-			// if we paused the game, unmute/undeafen all players
-			if !dgs.Running {
-				err = bot.applyToAll(dgs, false, false)
+		case command.End.Name:
+			return bot.endGame(gsr, userSett)
+
+		case command.PersonalLink.Name:
+			action := command.GetPersonalLinkParams(i.ApplicationCommandData().Options)
+			var info command.NewInfo
+			switch action {
+			case command.PersonalLinkShow:
+				info.ConnectCode, err = storage.GetPersonalConnectCode(bot.PostgresInterface, i.Member.User.ID)
+				if err != nil || info.ConnectCode != "" {
+					break
+				}
+				fallthrough
+			case command.PersonalLinkRegenerate:
+				action = command.PersonalLinkRegenerate
+				info.ConnectCode = bot.generateConnectCode()
+				if info.ConnectCode == "" {
+					return command.NewResponse(command.NewConnectCodeError, command.NewInfo{}, userSett)
+				}
+				err = storage.SetPersonalConnectCode(bot.PostgresInterface, i.Member.User.ID, info.ConnectCode)
+			case command.PersonalLinkRevoke:
+				err = storage.DeletePersonalConnectCode(bot.PostgresInterface, i.Member.User.ID)
 			}
-			bot.DispatchRefreshOrEdit(dgs, gsr, sett)
-			if err != nil {
-				return command.PrivateErrorResponse(command.Pause.Name, err, sett)
+			if info.ConnectCode != "" {
+				info.Hyperlink, info.MinimalURL = formCaptureURL(bot.url, info.ConnectCode, "")
 			}
-			return command.PrivateResponse(ThumbsUp)
+			return command.PersonalLinkResponse(action, info, err, userSett)
 
-		case command.End.Name:
-			if !isPermissioned {
-				return command.InsufficientPermissionsResponse(sett)
-			}
-			dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
-			if dgs != nil {
-				if !dgs.GameStateMsg.Exists() {
-					return command.NoGameResponse(sett)
+		case command.Profile.Name:
+			action, names, color := command.GetProfileParams(i.ApplicationCommandData().Options)
+			switch action {
+			case command.ProfileSet:
+				if names == nil {
+					return command.ProfileResponse(command.ProfileInvalidNames, action, nil, "", nil, userSett)
 				}
-
-				if v, ok := bot.EndGameChannels[dgs.ConnectCode]; ok {
-					v <- true
+				profile := storage.UserProfile{
+					UserID:         i.Member.User.ID,
+					InGameNames:    names,
+					PreferredColor: storage.NoPreferredColor,
 				}
-				delete(bot.EndGameChannels, dgs.ConnectCode)
-
-				err = bot.applyToAll(dgs, false, false)
+				if color != "" {
+					profile.PreferredColor = game.ColorStrings[color]
+				}
+				err = storage.SetUserProfile(bot.PostgresInterface, profile)
+				bot.profilesChanged()
+				return command.ProfileResponse(command.ProfileSuccess, action, names, color, err, userSett)
+			case command.ProfileShow:
+				profile, err := storage.GetUserProfile(bot.PostgresInterface, i.Member.User.ID)
+				if err == nil && profile == nil {
+					return command.ProfileResponse(command.ProfileNotFound, action, nil, "", nil, userSett)
+				} else if err != nil {
+					return command.ProfileResponse(command.ProfileSuccess, action, nil, "", err, userSett)
+				}
+				if profile.PreferredColor != storage.NoPreferredColor {
+					color = game.GetColorStringForInt(profile.PreferredColor)
+				}
+				return command.ProfileResponse(command.ProfileSuccess, action, profile.InGameNames, color, nil, userSett)
+			case command.ProfileClear:
+				err = storage.DeleteUserProfile(bot.PostgresInterface, i.Member.User.ID)
+				bot.profilesChanged()
+				return command.ProfileResponse(command.ProfileSuccess, action, nil, "", err, userSett)
+			}
+
+		case command.Links.Name:
+			action, userID, name := command.GetLinksParams(bot.PrimarySession, i.Member.User.ID, i.ApplicationCommandData().Options)
+			switch action {
+			case command.LinksList:
+				links, err := bot.getLinksForUser(i.GuildID, userID)
+				return command.LinksResponse(action, command.LinksSuccess, userID, "", links, err, userSett)
+			case command.LinksRemove:
+				if userID != i.Member.User.ID && !isAdmin && !granted {
+					return command.InsufficientPermissionsResponse(userSett)
+				}
+				names, err := bot.RedisInterface.GetUsernameOrUserIDMappings(i.GuildID, userID)
 				if err != nil {
-					return command.PrivateErrorResponse(command.End.Name, err, sett)
+					return command.LinksResponse(action, command.LinksSuccess, userID, name, nil, err, userSett)
 				}
-				return command.PrivateResponse(ThumbsUp)
+				// names are remembered exactly as they were in-game, but nobody should have to get the case right
+				found := ""
+				for n := range names {
+					if strings.EqualFold(n, name) {
+						found = n
+						break
+					}
+				}
+				if found == "" {
+					return command.LinksResponse(action, command.LinksNotFound, userID, name, nil, nil, userSett)
+				}
+				err = bot.RedisInterface.DeleteUsernameLink(i.GuildID, userID, found)
+				return command.LinksResponse(action, command.LinksSuccess, userID, found, nil, err, userSett)
+			case command.LinksAdd:
+				if !command.ValidLinkName(name) {
+					return command.LinksResponse(action, command.LinksInvalidName, userID, name, nil, nil, userSett)
+				}
+				err = bot.RedisInterface.AddUsernameLink(i.GuildID, userID, name)
+				return command.LinksResponse(action, command.LinksSuccess, userID, name, nil, err, userSett)
 			}
-			return command.DeadlockGameStateResponse(command.End.Name, sett)
 
 		case command.Privacy.Name:
 			privArg := command.GetPrivacyParam(i.ApplicationCommandData().Options)
-			switch privArg {
-			case command.PrivacyInfo:
-				return command.PrivacyResponse(privArg, nil, nil, nil, sett)
-
-			case command.PrivacyOptOut:
-				err = bot.RedisInterface.DeleteLinksByUserID(i.GuildID, i.Member.User.ID)
-				if err != nil {
-					return command.PrivacyResponse(privArg, nil, nil, err, sett)
-				}
-				fallthrough
-			case command.PrivacyOptIn:
-				err = bot.PostgresInterface.OptUserByString(i.Member.User.ID, privArg == command.PrivacyOptIn)
-				return command.PrivacyResponse(privArg, nil, nil, err, sett)
-
-			case command.PrivacyShowMe:
-				cached, _ := bot.RedisInterface.GetUsernameOrUserIDMappings(i.GuildID, i.Member.User.ID)
-				user, err := bot.PostgresInterface.GetUserByString(i.Member.User.ID)
-				return command.PrivacyResponse(privArg, cached, user, err, sett)
-			}
+			return bot.privacyResponse(i.GuildID, i.Member.User.ID, privArg, userSett)
 
 		case command.Map.Name:
 			mapType, detailed := command.GetMapParams(i.ApplicationCommandData().Options)
-			return command.MapResponse(mapType, detailed)
+			return command.MapResponse(baseMapURL, mapType, detailed)
 
 		case command.Stats.Name:
 			action, opType, id := command.GetStatsParams(bot.PrimarySession, i.GuildID, i.ApplicationCommandData().Options)
 			prem := true
 			tier, days, err := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, bot.TopGGClient, i.GuildID, i.Member.User.ID)
 			if err != nil {
-				log.Println("Error in /stats getPremium:", err)
+				guildLogger(i.GuildID).Error("Couldn't get the premium status for /stats", "err", err)
 			}
 			if premium.IsExpired(tier, days) {
 				prem = false
@@ -403,13 +518,15 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 					embed = bot.UserStatsEmbed(id, i.GuildID, sett, prem)
 				case command.Guild:
 					embed = bot.GuildStatsEmbed(i.GuildID, sett, prem)
+				case command.Me:
+					embed = bot.GlobalUserStatsEmbed(i.Member.User, sett)
 				case command.Match:
 					if MatchIDRegex.Match([]byte(id)) {
 						tokens := strings.Split(id, ":")
 						embed = bot.GameStatsEmbed(i.GuildID, tokens[1], tokens[0], prem, sett)
 					} else {
 						err := fmt.Errorf("invalid match code provided: %s, should resemble something like `1A2B3C4D:12345`", id)
-						return command.PrivateErrorResponse(command.Stats.Name+" "+command.Match, err, sett)
+						return command.PrivateErrorResponse(command.Stats.Name+" "+command.Match, err, userSett)
 					}
 				}
 				if embed != nil {
@@ -424,30 +541,30 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 				}
 			} else if action == setting.Clear {
 				// id mismatch applies to user ids AND guild ID (guildId *always* != author.id, therefore, must be admin)
-				if id != i.Member.User.ID && !isAdmin {
-					return command.InsufficientPermissionsResponse(sett)
+				if id != i.Member.User.ID && !isAdmin && !granted {
+					return command.InsufficientPermissionsResponse(userSett)
 				}
 				var content string
 				var components []discordgo.MessageComponent
 				switch opType {
 				case command.User:
-					content = sett.LocalizeMessage(&i18n.Message{
+					content = userSett.LocalizeMessage(&i18n.Message{
 						ID:    "commands.stats.user.reset.confirmation",
 						Other: "⚠️**Are you sure?**⚠️\nDo you really want to reset the stats for {{.User}}?\nThis process cannot be undone!",
 					},
 						map[string]interface{}{
 							"User": discord.MentionByUserID(id),
 						})
-					components = confirmationComponents(resetUserConfirmedID, resetUserCanceledID, sett)
+					components = confirmationComponents(resetUserConfirmedID, resetUserCanceledID, resetButtonLabel(userSett), userSett)
 				case command.Guild:
-					content = sett.LocalizeMessage(&i18n.Message{
+					content = userSett.LocalizeMessage(&i18n.Message{
 						ID:    "commands.stats.guild.reset.confirmation",
 						Other: "⚠️**Are you sure?**⚠️\nDo you really want to reset the stats for **{{.Guild}}**?\nThis process cannot be undone!",
 					},
 						map[string]interface{}{
 							"Guild": g.Name,
 						})
-					components = confirmationComponents(resetGuildConfirmedID, resetGuildCanceledID, sett)
+					components = confirmationComponents(resetGuildConfirmedID, resetGuildCanceledID, resetButtonLabel(userSett), userSett)
 				}
 				return &discordgo.InteractionResponse{
 					Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -463,55 +580,86 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			premArg := command.GetPremiumParams(i.ApplicationCommandData().Options)
 			premStatus, days, err := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, bot.TopGGClient, i.GuildID, i.Member.User.ID)
 			if err != nil {
-				log.Println("Err in /premium get guild prem:", err)
+				guildLogger(i.GuildID).Error("Couldn't get the premium status for /premium", "err", err)
 			}
 			if premium.IsExpired(premStatus, days) {
 				premStatus = premium.FreeTier
 			}
 			return command.PremiumResponse(i.GuildID, premStatus, days, premArg, isAdmin, sett)
 
+		case command.Owner.Name:
+			return bot.ownerCommandHandler(i, userSett)
+
 		case command.Debug.Name:
 			action, opType, id := command.GetDebugParams(bot.PrimarySession, i.Member.User.ID, i.ApplicationCommandData().Options)
 			if action == setting.View {
 				if opType == command.User {
 					cached, err := bot.RedisInterface.GetUsernameOrUserIDMappings(i.GuildID, id)
-					log.Println("View user cache")
-					return command.DebugResponse(setting.View, cached, nil, id, err, sett)
+					guildLogger(i.GuildID).Debug("Viewing the user cache", "user", id)
+					return command.DebugResponse(setting.View, cached, nil, id, err, userSett)
 				} else if opType == command.GameState {
 					state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
 					if state != nil {
 						jBytes, err := json.MarshalIndent(state, "", "  ")
-						return command.DebugResponse(setting.View, nil, jBytes, id, err, sett)
+						return command.DebugResponse(setting.View, nil, jBytes, id, err, userSett)
 					} else {
-						return command.DeadlockGameStateResponse(command.Debug.Name, sett)
+						return command.DeadlockGameStateResponse(command.Debug.Name, userSett)
+					}
+				} else if opType == command.Jobs {
+					state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+					if state == nil {
+						return command.DeadlockGameStateResponse(command.Debug.Name, userSett)
+					}
+					history := bot.RedisInterface.GetJobHistory(state.ConnectCode)
+					failures := bot.RedisInterface.GetDeadLetters(state.ConnectCode)
+					return command.DebugJobsResponse(history, failures, userSett)
+				} else if opType == command.NameMatches {
+					state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+					if state == nil {
+						return command.DeadlockGameStateResponse(command.Debug.Name, userSett)
 					}
+					return command.DebugNameMatchesResponse(bot.RedisInterface.GetNameMatchHistory(state.ConnectCode), userSett)
 				}
 			} else if action == setting.Clear {
 				if opType == command.User {
 					if id != i.Member.User.ID {
-						if !isAdmin {
-							return command.InsufficientPermissionsResponse(sett)
+						if !isAdmin && !granted {
+							return command.InsufficientPermissionsResponse(userSett)
 						}
 					}
 					err := bot.RedisInterface.DeleteLinksByUserID(i.GuildID, id)
-					return command.DebugResponse(setting.Clear, nil, nil, id, err, sett)
+					return command.DebugResponse(setting.Clear, nil, nil, id, err, userSett)
 				}
 			} else if action == command.UnmuteAll {
-				dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
-				err = bot.applyToAll(dgs, false, false)
-				if err != nil {
-					return command.PrivateErrorResponse(command.UnmuteAll, err, sett)
-				}
-				return command.PrivateResponse(ThumbsUp)
+				return bot.unmuteAll(gsr, userSett)
 			}
 		}
 
 	} else if i.Type == discordgo.InteractionMessageComponent {
-		if redis_common.IsUserRateLimitedSpecific(bot.RedisInterface.client, i.Member.User.ID, i.MessageComponentData().CustomID) {
-			banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, i.Member.User.ID)
-			return softbanResponse(banned, sett)
+		if result := bot.rateLimit(i.Member.User.ID, i.GuildID, redis_common.ComponentsBucket); !result.Allowed {
+			return bot.rateLimitResponse(result, userSett)
+		}
+
+		granted := false
+		if key := componentPermissionKey(i.MessageComponentData().CustomID); key != "" {
+			ext := bot.StorageInterface.GetExtendedGuildSettings(i.GuildID)
+			var permitted bool
+			if permitted, granted = commandPermitted(key, i.Member, ext, isAdmin, isPermissioned); !permitted {
+				return command.InsufficientPermissionsResponse(userSett)
+			}
+		}
+		if command.IsHelpPageCustomID(i.MessageComponentData().CustomID) {
+			return command.HelpPageResponse(i.MessageComponentData().CustomID, userSett, bot.StorageInterface.GetExtendedGuildSettings(i.GuildID))
+		}
+		if strings.HasPrefix(i.MessageComponentData().CustomID, nameMatchIDPrefix) {
+			return bot.handleNameMatchResponse(i, gsr, sett, userSett)
+		}
+		if resp, handled := bot.handleGameControl(i, gsr, sett, userSett); handled {
+			return resp
+		}
+		if strings.HasPrefix(i.MessageComponentData().CustomID, linkUserIDPrefix) {
+			return bot.handleLinkUserSelect(i, gsr, sett, userSett)
 		}
-		redis_common.MarkUserRateLimit(bot.RedisInterface.client, i.Member.User.ID, i.MessageComponentData().CustomID, redis_common.GlobalUserRateLimitDuration)
 
 		switch i.MessageComponentData().CustomID {
 		case colorSelectID:
@@ -519,13 +667,13 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 				value := i.MessageComponentData().Values[0]
 				lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
 				if lock == nil {
-					log.Printf("No lock could be obtained when linking for guild %s, channel %s\n", i.GuildID, i.ChannelID)
-					return command.DeadlockGameStateResponse(command.Link.Name, sett)
+					guildLogger(i.GuildID).Warn("No lock could be obtained when linking", "channel", i.ChannelID)
+					return command.DeadlockGameStateResponse(command.Link.Name, userSett)
 				}
 				if value == UnlinkEmojiName {
 					value = ""
 				}
-				resp, success := bot.linkOrUnlinkAndRespond(dgs, i.Member.User.ID, value, sett)
+				resp, success := bot.linkOrUnlinkAndRespond(dgs, i.Member.User.ID, value, userSett)
 				if success {
 					bot.RedisInterface.SetDiscordGameState(dgs, lock)
 					bot.DispatchRefreshOrEdit(dgs, gsr, sett)
@@ -544,9 +692,13 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			// a bit dirty way but works :P
 			if len(i.Message.Mentions) == 1 {
 				id := i.Message.Mentions[0].ID
+				// the same check as /stats clear user, for whoever pressed the button
+				if id != i.Member.User.ID && !isAdmin && !granted {
+					return command.InsufficientPermissionsResponse(userSett)
+				}
 				err := bot.PostgresInterface.DeleteAllGamesForUser(id)
 				if err != nil {
-					content = sett.LocalizeMessage(&i18n.Message{
+					content = userSett.LocalizeMessage(&i18n.Message{
 						ID:    "commands.stats.user.reset.error",
 						Other: "Encountered an error resetting the stats for {{.User}}: {{.Error}}",
 					},
@@ -555,7 +707,7 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 							"Error": err.Error(),
 						})
 				} else {
-					content = sett.LocalizeMessage(&i18n.Message{
+					content = userSett.LocalizeMessage(&i18n.Message{
 						ID:    "commands.stats.user.reset.success",
 						Other: "Successfully reset the stats for {{.User}}!",
 					},
@@ -564,7 +716,7 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 						})
 				}
 			} else {
-				content = sett.LocalizeMessage(&i18n.Message{
+				content = userSett.LocalizeMessage(&i18n.Message{
 					ID:    "commands.stats.user.reset.notfound",
 					Other: "Failed to gather user from message!",
 				})
@@ -585,7 +737,7 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			var content string
 			err := bot.PostgresInterface.DeleteAllGamesForServer(i.GuildID)
 			if err != nil {
-				content = sett.LocalizeMessage(&i18n.Message{
+				content = userSett.LocalizeMessage(&i18n.Message{
 					ID:    "commands.stats.guild.reset.error",
 					Other: "Encountered an error resetting the stats for this guild: {{.Error}}",
 				},
@@ -593,7 +745,7 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 						"Error": err.Error(),
 					})
 			} else {
-				content = sett.LocalizeMessage(&i18n.Message{
+				content = userSett.LocalizeMessage(&i18n.Message{
 					ID:    "commands.stats.guild.reset.success",
 					Other: "Successfully reset the stats for **{{.Guild}}**!",
 				},
@@ -617,13 +769,13 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 			if i.Message.MessageReference != nil {
 				bot.deleteComponentInParentMessage(s, i)
 			}
-			return resetCancelResponse(sett)
+			return resetCancelResponse(userSett)
 
 		case resetGuildCanceledID:
 			if i.Message.MessageReference != nil {
 				bot.deleteComponentInParentMessage(s, i)
 			}
-			return resetCancelResponse(sett)
+			return resetCancelResponse(userSett)
 		}
 	}
 
@@ -631,13 +783,58 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 	return nil
 }
 
+// privacyResponse handles the privacy subcommands. guildID is empty in DMs, where there are no guild-specific links
+// to delete or show
+func (bot *Bot) privacyResponse(guildID, userID, privArg string, sett *settings.GuildSettings) *discordgo.InteractionResponse {
+	switch privArg {
+	case command.PrivacyInfo:
+		return command.PrivacyResponse(privArg, nil, nil, nil, "", nil, sett)
+
+	case command.PrivacyOptOut:
+		// opting out is for every server, even from DMs, so the links, profile and personal connect code go everywhere
+		err := bot.RedisInterface.DeleteAllLinksByUserID(userID)
+		if err != nil {
+			return command.PrivacyResponse(privArg, nil, nil, nil, "", err, sett)
+		}
+		err = storage.DeleteUserProfile(bot.PostgresInterface, userID)
+		if err == nil {
+			err = storage.DeletePersonalConnectCode(bot.PostgresInterface, userID)
+		}
+		if err != nil {
+			return command.PrivacyResponse(privArg, nil, nil, nil, "", err, sett)
+		}
+		bot.profilesChanged()
+		fallthrough
+	case command.PrivacyOptIn:
+		err := bot.PostgresInterface.OptUserByString(userID, privArg == command.PrivacyOptIn)
+		return command.PrivacyResponse(privArg, nil, nil, nil, "", err, sett)
+
+	case command.PrivacyShowMe:
+		var cached map[string]interface{}
+		if guildID != "" {
+			cached, _ = bot.RedisInterface.GetUsernameOrUserIDMappings(guildID, userID)
+		}
+		profile, err := storage.GetUserProfile(bot.PostgresInterface, userID)
+		if err != nil {
+			guildLogger(guildID).Error("Couldn't get the user's profile", "user", userID, "err", err)
+		}
+		personalCode, err := storage.GetPersonalConnectCode(bot.PostgresInterface, userID)
+		if err != nil {
+			guildLogger(guildID).Error("Couldn't get the user's personal connect code", "user", userID, "err", err)
+		}
+		user, err := bot.PostgresInterface.GetUserByString(userID)
+		return command.PrivacyResponse(privArg, cached, user, profile, personalCode, err, sett)
+	}
+	return nil
+}
+
 func (bot *Bot) linkOrUnlinkAndRespond(dgs *GameState, userID, testValue string, sett *settings.GuildSettings) (*discordgo.InteractionResponse, bool) {
 	if testValue != "" {
 		// don't care if it's successful, just always unlink before linking
 		unlinkPlayer(dgs, userID)
 		status, err := linkPlayer(bot.RedisInterface, dgs, userID, testValue)
 		if err != nil {
-			log.Println(err)
+			dgs.logger().Error("Couldn't link the player", "user", userID, "err", err)
 		}
 		return command.LinkResponse(status, userID, testValue, sett), status == command.LinkSuccess
 	} else {
@@ -654,21 +851,25 @@ func (bot *Bot) deleteComponentInParentMessage(s *discordgo.Session, i *discordg
 	me.Components = []discordgo.MessageComponent{}
 	_, err := s.ChannelMessageEditComplex(me)
 	if err != nil {
-		log.Println("Error when attempting to edit complex message", err)
+		guildLogger(i.GuildID).Error("Couldn't remove the components from the message", "message", i.Message.ID, "err", err)
 	}
 }
 
-func confirmationComponents(confirmedID string, canceledID string, sett *settings.GuildSettings) []discordgo.MessageComponent {
+func resetButtonLabel(sett *settings.GuildSettings) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.stats.reset.button.proceed",
+		Other: "RESET",
+	})
+}
+
+func confirmationComponents(confirmedID, canceledID, proceedLabel string, sett *settings.GuildSettings) []discordgo.MessageComponent {
 	return []discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
 					CustomID: confirmedID,
 					Style:    discordgo.DangerButton,
-					Label: sett.LocalizeMessage(&i18n.Message{
-						ID:    "commands.stats.reset.button.proceed",
-						Other: "RESET",
-					}),
+					Label:    proceedLabel,
 				},
 				discordgo.Button{
 					CustomID: canceledID,
@@ -698,31 +899,6 @@ func resetCancelResponse(sett *settings.GuildSettings) *discordgo.InteractionRes
 	}
 }
 
-func softbanResponse(banned bool, sett *settings.GuildSettings) *discordgo.InteractionResponse {
-	if banned {
-		return &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Flags: 1 << 6, //private message
-				Content: sett.LocalizeMessage(&i18n.Message{
-					ID:    "softban.ignoring",
-					Other: "I'm ignoring you for the next 5 minutes, stop spamming",
-				}),
-			},
-		}
-	}
-	return &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Flags: 1 << 6, //private message
-			Content: sett.LocalizeMessage(&i18n.Message{
-				ID:    "softban.warning",
-				Other: "Please stop spamming commands",
-			}),
-		},
-	}
-}
-
 func checkPermissions(perm int64, perms []int64) (a int64) {
 	for _, v := range perms {
 		if v&perm != v {
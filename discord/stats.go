@@ -112,20 +112,7 @@ func (bot *Bot) UserStatsEmbed(userID, guildID string, sett *settings.GuildSetti
 
 		guildsPlayedIn := bot.PostgresInterface.NumGuildsPlayedInByUser(userID)
 		if guildsPlayedIn > 0 {
-			val := sett.LocalizeMessage(&i18n.Message{
-				ID:    "responses.userStatsEmbed.ServersPlayedInValue",
-				Other: "{{.Servers}} Servers",
-			}, map[string]interface{}{
-				"Servers": guildsPlayedIn,
-			})
-			if guildsPlayedIn == 1 {
-				val = sett.LocalizeMessage(&i18n.Message{
-					ID:    "responses.userStatsEmbed.ServerPlayedInValue",
-					Other: "{{.Server}} Server",
-				}, map[string]interface{}{
-					"Server": guildsPlayedIn,
-				})
-			}
+			val := serversPlayedInValue(guildsPlayedIn, sett)
 			fields = append(fields, &discordgo.MessageEmbedField{
 				Name: sett.LocalizeMessage(&i18n.Message{
 					ID:    "responses.userStatsEmbed.ServersPlayedIn",
@@ -497,6 +484,98 @@ func (bot *Bot) UserStatsEmbed(userID, guildID string, sett *settings.GuildSetti
 	return &embed
 }
 
+// GlobalUserStatsEmbed is the user's stats across every server they've played in. It doesn't need a guild, so it also
+// works in DMs
+func (bot *Bot) GlobalUserStatsEmbed(user *discordgo.User, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+	gamesPlayed := bot.PostgresInterface.NumGamesPlayedByUser(user.ID)
+	wins := bot.PostgresInterface.NumWins(user.ID)
+	guildsPlayedIn := bot.PostgresInterface.NumGuildsPlayedInByUser(user.ID)
+
+	winrate := 0.0
+	if gamesPlayed > 0 {
+		winrate = 100.0 * (float64(wins) / float64(gamesPlayed))
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.userStatsEmbed.GamesPlayed",
+				Other: "Games Played",
+			}),
+			Value:  fmt.Sprintf("%d", gamesPlayed),
+			Inline: true,
+		},
+		{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.userStatsEmbed.TotalWins",
+				Other: "Total Wins",
+			}),
+			Value:  fmt.Sprintf("%d", wins),
+			Inline: true,
+		},
+		{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.userStatsEmbed.Winrate",
+				Other: "Winrate",
+			}),
+			Value:  fmt.Sprintf("%d/%d | %.0f%%", wins, gamesPlayed, winrate),
+			Inline: true,
+		},
+		{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.userStatsEmbed.ServersPlayedIn",
+				Other: "Played In",
+			}),
+			Value:  serversPlayedInValue(guildsPlayedIn, sett),
+			Inline: true,
+		},
+	}
+	for _, role := range []game.GameRole{game.CrewmateRole, game.ImposterRole} {
+		total := bot.PostgresInterface.NumGamesAsRole(user.ID, int16(role))
+		if total <= 0 {
+			continue
+		}
+		roleWins := bot.PostgresInterface.NumWinsAsRole(user.ID, int16(role))
+		name := sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.userStatsEmbed.CrewmateWins",
+			Other: "Crewmate Wins",
+		})
+		if role == game.ImposterRole {
+			name = sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.userStatsEmbed.ImposterWins",
+				Other: "Imposter Wins",
+			})
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: name,
+			Value: fmt.Sprintf("%d/%d %s | %.0f%%", roleWins, total,
+				sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.stats.Games",
+					Other: "Games",
+				}),
+				100.0*float64(roleWins)/float64(total)),
+			Inline: true,
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.userStatsEmbed.Title",
+			Other: "User Stats",
+		}),
+		Description: sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.userStatsEmbed.GlobalDesc",
+			Other: "User stats for {{.User}} across every server",
+		}, map[string]interface{}{
+			"User": "<@!" + user.ID + ">",
+		}),
+		Color: 3066993, // GREEN
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: user.AvatarURL(""),
+		},
+		Fields: fields,
+	}
+}
+
 func (bot *Bot) CheckOrFetchCachedUserData(userID, guildID string) (string, string, string) {
 	info := rediskey.GetCachedUserInfo(context.Background(), bot.RedisInterface.client, userID, guildID)
 	if info == "" {
@@ -545,7 +624,7 @@ func (bot *Bot) GuildStatsEmbed(guildID string, sett *settings.GuildSettings, is
 		gname = guildID
 	} else {
 		gname = g.Name
-		avatarURL = g.IconURL()
+		avatarURL = g.IconURL("")
 	}
 
 	gamesPlayed := bot.PostgresInterface.NumGamesPlayedOnGuild(guildID)
@@ -947,3 +1026,20 @@ func TrimEmbedFields(fields []*discordgo.MessageEmbedField) []*discordgo.Message
 
 	return fields[:i]
 }
+
+func serversPlayedInValue(guildsPlayedIn int64, sett *settings.GuildSettings) string {
+	if guildsPlayedIn == 1 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.userStatsEmbed.ServerPlayedInValue",
+			Other: "{{.Server}} Server",
+		}, map[string]interface{}{
+			"Server": guildsPlayedIn,
+		})
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "responses.userStatsEmbed.ServersPlayedInValue",
+		Other: "{{.Servers}} Servers",
+	}, map[string]interface{}{
+		"Servers": guildsPlayedIn,
+	})
+}
@@ -1,6 +1,8 @@
 package discord
 
 import (
+	"encoding/json"
+
 	"github.com/automuteus/automuteus/amongus"
 	"github.com/bwmarrin/discordgo"
 )
@@ -19,6 +21,18 @@ type UserData struct {
 	ShouldBeMute bool   `json:"ShouldBeMute"`
 	ShouldBeDeaf bool   `json:"ShouldBeDeaf"`
 	InGameName   string `json:"PlayerName"`
+	PlayerColor  int    `json:"PlayerColor"`
+}
+
+// UnmarshalJSON treats users saved before links included the color as linked to no color, rather than to red
+func (user *UserData) UnmarshalJSON(data []byte) error {
+	type userData UserData
+	aux := userData{PlayerColor: amongus.UnlinkedPlayer.Color}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*user = UserData(aux)
+	return nil
 }
 
 func MakeUserDataFromDiscordUser(dUser *discordgo.User, nick string) UserData {
@@ -32,6 +46,7 @@ func MakeUserDataFromDiscordUser(dUser *discordgo.User, nick string) UserData {
 		ShouldBeDeaf: false,
 		ShouldBeMute: false,
 		InGameName:   amongus.UnlinkedPlayerName,
+		PlayerColor:  amongus.UnlinkedPlayer.Color,
 	}
 }
 
@@ -56,6 +71,22 @@ func (user *UserData) GetPlayerName() string {
 	return user.InGameName
 }
 
+func (user *UserData) GetPlayerColor() int {
+	return user.PlayerColor
+}
+
+// IsLinkedTo returns true if the user is linked to the player. Names aren't unique within a lobby, so the color has
+// to match too
+func (user *UserData) IsLinkedTo(player amongus.PlayerData) bool {
+	return user.InGameName == player.Name && user.PlayerColor == player.Color
+}
+
 func (user *UserData) Link(player amongus.PlayerData) {
 	user.InGameName = player.Name
+	user.PlayerColor = player.Color
+}
+
+func (user *UserData) Unlink() {
+	user.InGameName = amongus.UnlinkedPlayerName
+	user.PlayerColor = amongus.UnlinkedPlayer.Color
 }
@@ -0,0 +1,59 @@
+package discord
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// custom IDs are of the form link-user:<userID>
+const linkUserIDPrefix = "link-user:"
+
+func linkUserCustomID(userID string) string {
+	return linkUserIDPrefix + userID
+}
+
+// playerSelectMenuOptions is a select option for every player actually in the game, ordered by color
+func (dgs *GameState) playerSelectMenuOptions(emojis []Emoji) []discordgo.SelectMenuOption {
+	colors := make([]int, 0, len(dgs.GameData.PlayerData))
+	for color := range dgs.GameData.PlayerData {
+		if color >= 0 && color < len(emojis) {
+			colors = append(colors, color)
+		}
+	}
+	sort.Ints(colors)
+
+	options := make([]discordgo.SelectMenuOption, len(colors))
+	for i, color := range colors {
+		options[i] = emojis[color].toSelectMenuOption(game.GetColorStringForInt(color))
+		options[i].Description = dgs.GameData.PlayerData[color].Name
+	}
+	return options
+}
+
+// handleLinkUserSelect links the user picked with the "Link to color…" context menu to the selected color
+func (bot *Bot) handleLinkUserSelect(i *discordgo.InteractionCreate, gsr GameStateRequest, sett, userSett *settings.GuildSettings) *discordgo.InteractionResponse {
+	userID := strings.TrimPrefix(i.MessageComponentData().CustomID, linkUserIDPrefix)
+	if len(i.MessageComponentData().Values) == 0 {
+		return nil
+	}
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
+	if lock == nil {
+		guildLogger(i.GuildID).Warn("No lock could be obtained when linking", "channel", i.ChannelID)
+		return command.DeadlockGameStateResponse(command.LinkUser.Name, userSett)
+	}
+	resp, success := bot.linkOrUnlinkAndRespond(dgs, userID, i.MessageComponentData().Values[0], userSett)
+	if success {
+		bot.RedisInterface.SetDiscordGameState(dgs, lock)
+		bot.DispatchRefreshOrEdit(dgs, gsr, sett)
+	} else {
+		// only release the lock; no changes
+		bot.RedisInterface.SetDiscordGameState(nil, lock)
+	}
+	// replace the select menu with the result, so it can't be used again
+	return updateMessageResponse(resp)
+}
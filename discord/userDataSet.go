@@ -2,9 +2,9 @@ package discord
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
 )
 
 type UserDataSet map[string]UserData
@@ -20,16 +20,40 @@ func (dgs *GameState) GetCountLinked() int {
 	return LinkedPlayerCount
 }
 
-func (dgs *GameState) AttemptPairingByMatchingNames(data amongus.PlayerData) string {
-	name := strings.ReplaceAll(strings.ToLower(data.Name), " ", "")
+// AttemptPairingByMatchingNames scores the player's name against the username and nickname of every user who isn't
+// already linked to another player. A single high-confidence match is linked immediately; otherwise the decision says
+// whether the best candidate is worth asking about
+func (dgs *GameState) AttemptPairingByMatchingNames(data amongus.PlayerData) amongus.NameMatchDecision {
+	bestID, bestScore, runnerUpScore := "", 0.0, 0.0
 	for userID, v := range dgs.UserData {
-		if strings.ReplaceAll(strings.ToLower(v.GetUserName()), " ", "") == name || strings.ReplaceAll(strings.ToLower(v.GetNickName()), " ", "") == name {
-			v.Link(data)
-			dgs.UserData[userID] = v
-			return userID
+		if v.GetPlayerName() != amongus.UnlinkedPlayerName && !v.IsLinkedTo(data) {
+			continue
+		}
+		score := amongus.NameMatchScore(data.Name, v.GetUserName())
+		if nickScore := amongus.NameMatchScore(data.Name, v.GetNickName()); nickScore > score {
+			score = nickScore
+		}
+		if score > bestScore {
+			bestID, bestScore, runnerUpScore = userID, score, bestScore
+		} else if score > runnerUpScore {
+			runnerUpScore = score
 		}
 	}
-	return ""
+
+	switch {
+	case bestScore < amongus.MediumConfidenceMatch:
+		return amongus.NewNameMatchDecision(data, "", bestScore, amongus.NameMatchNone)
+	// two users match about as well as each other; better to link neither than the wrong one
+	case runnerUpScore >= amongus.MediumConfidenceMatch && bestScore-runnerUpScore < 0.1:
+		return amongus.NewNameMatchDecision(data, "", bestScore, amongus.NameMatchAmbiguous)
+	case bestScore >= amongus.HighConfidenceMatch:
+		v := dgs.UserData[bestID]
+		v.Link(data)
+		dgs.UserData[bestID] = v
+		return amongus.NewNameMatchDecision(data, bestID, bestScore, amongus.NameMatchLinked)
+	default:
+		return amongus.NewNameMatchDecision(data, bestID, bestScore, amongus.NameMatchPrompted)
+	}
 }
 
 func (dgs *GameState) UpdateUserData(userID string, data UserData) {
@@ -38,6 +62,40 @@ func (dgs *GameState) UpdateUserData(userID string, data UserData) {
 	}
 }
 
+// AttemptPairingByProfiles links the player to the unlinked user whose profile has their name. If several users play
+// under that name, the one who prefers the player's color is linked, if there's exactly one
+func (dgs *GameState) AttemptPairingByProfiles(data amongus.PlayerData, profiles []storage.UserProfile) string {
+	var candidates []storage.UserProfile
+	for _, profile := range profiles {
+		if v, ok := dgs.UserData[profile.UserID]; ok {
+			if v.IsLinkedTo(data) {
+				// already linked to this player
+				return profile.UserID
+			}
+			if v.GetPlayerName() == amongus.UnlinkedPlayerName {
+				candidates = append(candidates, profile)
+			}
+		}
+	}
+	if len(candidates) > 1 {
+		var preferred []storage.UserProfile
+		for _, profile := range candidates {
+			if profile.PreferredColor == data.Color {
+				preferred = append(preferred, profile)
+			}
+		}
+		candidates = preferred
+	}
+	if len(candidates) != 1 {
+		return ""
+	}
+	userID := candidates[0].UserID
+	v := dgs.UserData[userID]
+	v.Link(data)
+	dgs.UserData[userID] = v
+	return userID
+}
+
 func (dgs *GameState) AttemptPairingByUserIDs(data amongus.PlayerData, userIDs map[string]interface{}) string {
 	for userID := range userIDs {
 		if v, ok := dgs.UserData[userID]; ok {
@@ -54,17 +112,28 @@ func (dgs *GameState) AttemptPairingByUserIDs(data amongus.PlayerData, userIDs m
 
 func (dgs *GameState) ClearPlayerData(userID string) bool {
 	if v, ok := dgs.UserData[userID]; ok {
-		v.InGameName = amongus.UnlinkedPlayerName
+		v.Unlink()
 		dgs.UserData[userID] = v
 		return true
 	}
 	return false
 }
 
-func (dgs *GameState) ClearPlayerDataByPlayerName(playerName string) {
+func (dgs *GameState) ClearPlayerDataByPlayer(data amongus.PlayerData) {
+	for i, v := range dgs.UserData {
+		if v.IsLinkedTo(data) {
+			v.Unlink()
+			dgs.UserData[i] = v
+			return
+		}
+	}
+}
+
+// RelinkPlayerColor keeps the user linked to a player who changed color
+func (dgs *GameState) RelinkPlayerColor(name string, oldColor, color int) {
 	for i, v := range dgs.UserData {
-		if v.GetPlayerName() == playerName {
-			v.InGameName = amongus.UnlinkedPlayerName
+		if v.IsLinkedTo(amongus.PlayerData{Name: name, Color: oldColor}) {
+			v.PlayerColor = color
 			dgs.UserData[i] = v
 			return
 		}
@@ -73,7 +142,7 @@ func (dgs *GameState) ClearPlayerDataByPlayerName(playerName string) {
 
 func (dgs *GameState) UnlinkAllUsers() {
 	for i, v := range dgs.UserData {
-		v.InGameName = amongus.UnlinkedPlayerName
+		v.Unlink()
 		dgs.UserData[i] = v
 	}
 }
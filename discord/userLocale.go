@@ -0,0 +1,33 @@
+package discord
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/automuteus/utils/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// userSettings returns a copy of the guild's settings that localizes messages in the user's Discord locale if there's
+// a translation for it, and in the guild's language otherwise.
+// Only ephemeral responses should use it; anything the rest of the guild sees stays in the guild's language
+func userSettings(sett *settings.GuildSettings, userLocale discordgo.Locale) *settings.GuildSettings {
+	if userLocale == "" {
+		return sett
+	}
+	// GuildSettings has a lock, so it can't be copied by value
+	data, err := json.Marshal(sett)
+	if err != nil {
+		log.Println(err)
+		return sett
+	}
+	userSett := settings.MakeGuildSettings()
+	err = json.Unmarshal(data, userSett)
+	if err != nil {
+		log.Println(err)
+		return sett
+	}
+	// parsed like an Accept-Language header, so the guild's language is used if the user's isn't translated
+	userSett.Language = string(userLocale) + "," + sett.GetLanguage()
+	return userSett
+}
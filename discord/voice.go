@@ -7,7 +7,6 @@ import (
 	"github.com/automuteus/utils/pkg/task"
 	"github.com/bsm/redislock"
 	"github.com/bwmarrin/discordgo"
-	"log"
 	"strconv"
 	"time"
 )
@@ -68,7 +67,7 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
 
 		tracked := voiceState.ChannelID != "" && dgs.VoiceChannel == voiceState.ChannelID
 
-		_, linked := dgs.GameData.GetByName(userData.InGameName)
+		_, linked := dgs.GameData.GetPlayer(userData.GetPlayerName(), userData.GetPlayerColor())
 		// only actually tracked if we're in a tracked channel AND linked to a player
 		tracked = tracked && linked
 
@@ -79,7 +78,7 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
 				Mute:   mute,
 				Deaf:   deaf,
 			})
-			log.Println("Forcibly applying mute/deaf to " + userData.User.UserID)
+			dgs.logger().Debug("Forcibly applying mute/deaf", "user", userData.User.UserID, "mute", mute, "deaf", deaf)
 		}
 	}
 	if len(users) > 0 {
@@ -134,7 +133,7 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 
 		tracked := voiceState.ChannelID != "" && dgs.VoiceChannel == voiceState.ChannelID
 
-		auData, found := dgs.GameData.GetByName(userData.InGameName)
+		auData, found := dgs.GameData.GetPlayer(userData.GetPlayerName(), userData.GetPlayerColor())
 		// only actually tracked if we're in a tracked channel AND linked to a player
 		var isAlive bool
 
@@ -182,7 +181,7 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 	voiceLock := bot.RedisInterface.LockVoiceChanges(dgs.ConnectCode, time.Second*time.Duration(delay+1))
 
 	if delay > 0 {
-		log.Printf("Sleeping for %d seconds before applying changes to users\n", delay)
+		dgs.logger().Debug("Sleeping before applying changes to users", "delay", delay)
 		time.Sleep(time.Second * time.Duration(delay))
 	}
 
@@ -201,9 +200,9 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 			// no lock; we're not done yet
 			err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, nil)
 			if err != nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't issue the high priority mutes", "err", err)
 			} else {
-				log.Println("Successfully finished issuing high priority mutes")
+				dgs.logger().Debug("Successfully finished issuing high priority mutes")
 			}
 			rem := users[priorityRequests:]
 			if len(rem) > 0 {
@@ -213,21 +212,21 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 				}
 				err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
 				if err != nil {
-					log.Println(err)
+					dgs.logger().Error("Couldn't issue the remaining mutes", "err", err)
 				}
 			} else if voiceLock != nil {
 				voiceLock.Release(context.Background())
 			}
 		} else {
 			// no priority; issue all at once
-			log.Println("Issuing mutes/deafens with no particular priority")
+			dgs.logger().Debug("Issuing mutes/deafens with no particular priority")
 			req := task.UserModifyRequest{
 				Premium: premTier,
 				Users:   users,
 			}
 			err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
 			if err != nil {
-				log.Println(err)
+				dgs.logger().Error("Couldn't issue the mutes", "err", err)
 			}
 		}
 	}
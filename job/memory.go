@@ -0,0 +1,150 @@
+package job
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/automuteus/utils/pkg/task"
+)
+
+// MemorySource is an in-process Source backed by slices and channels. It is intended for tests and single-process
+// deployments, where jobs are pushed from the same process that handles them (no Redis or Galactus required)
+type MemorySource struct {
+	lock   sync.Mutex
+	queues map[string]*memoryQueue
+}
+
+type memoryQueue struct {
+	jobs        []string
+	sequence    int64
+	subscribers map[*memorySubscription]struct{}
+	acks        int
+}
+
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		queues: make(map[string]*memoryQueue),
+	}
+}
+
+// queue must be called with the lock held
+func (ms *MemorySource) queue(connectCode string) *memoryQueue {
+	q, ok := ms.queues[connectCode]
+	if !ok {
+		q = &memoryQueue{
+			jobs:        []string{},
+			subscribers: make(map[*memorySubscription]struct{}),
+		}
+		ms.queues[connectCode] = q
+	}
+	return q
+}
+
+// Push appends a sequenced job for the connect code and notifies any subscribers, mirroring task.PushJob
+func (ms *MemorySource) Push(connectCode string, jobType task.JobType, payload string) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	q := ms.queue(connectCode)
+	q.sequence++
+	// a job is only strings and numbers, so it can always be marshalled
+	jBytes, _ := json.Marshal(Job{
+		Job: task.Job{
+			JobType: jobType,
+			Payload: payload,
+		},
+		Sequence: q.sequence,
+	})
+	ms.pushRaw(q, string(jBytes))
+}
+
+// PushRaw appends a job exactly as provided, without validating or sequencing it
+func (ms *MemorySource) PushRaw(connectCode, raw string) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.pushRaw(ms.queue(connectCode), raw)
+}
+
+// pushRaw must be called with the lock held
+func (ms *MemorySource) pushRaw(q *memoryQueue, raw string) {
+	q.jobs = append(q.jobs, raw)
+	for sub := range q.subscribers {
+		signal(sub.notify)
+	}
+}
+
+func (ms *MemorySource) Subscribe(connectCode string) Subscription {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	sub := &memorySubscription{
+		source:      ms,
+		connectCode: connectCode,
+		notify:      make(chan struct{}, 1),
+	}
+	q := ms.queue(connectCode)
+	q.subscribers[sub] = struct{}{}
+	// jobs pushed before anyone was listening shouldn't be stranded
+	if len(q.jobs) > 0 {
+		signal(sub.notify)
+	}
+	return sub
+}
+
+func (ms *MemorySource) Pop(connectCode string) (Job, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	q, ok := ms.queues[connectCode]
+	if !ok || len(q.jobs) == 0 {
+		return Job{}, ErrNoJobs
+	}
+	raw := q.jobs[0]
+	q.jobs = q.jobs[1:]
+	return Parse(raw)
+}
+
+func (ms *MemorySource) Ack(connectCode string) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.queue(connectCode).acks++
+}
+
+// Acks returns how many times a listener has indicated it's ready for jobs for the connect code
+func (ms *MemorySource) Acks(connectCode string) int {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if q, ok := ms.queues[connectCode]; ok {
+		return q.acks
+	}
+	return 0
+}
+
+type memorySubscription struct {
+	source      *MemorySource
+	connectCode string
+	notify      chan struct{}
+	closed      bool
+}
+
+func (sub *memorySubscription) Notify() <-chan struct{} {
+	return sub.notify
+}
+
+func (sub *memorySubscription) Close() error {
+	sub.source.lock.Lock()
+	defer sub.source.lock.Unlock()
+
+	if sub.closed {
+		return nil
+	}
+	sub.closed = true
+	if q, ok := sub.source.queues[sub.connectCode]; ok {
+		delete(q.subscribers, sub)
+	}
+	close(sub.notify)
+	return nil
+}
@@ -0,0 +1,90 @@
+package job
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func TestMemorySource_PushPop(t *testing.T) {
+	ms := NewMemorySource()
+	if _, err := ms.Pop("ABCD1234"); !errors.Is(err, ErrNoJobs) {
+		t.Error("Popping from an empty source should return ErrNoJobs")
+	}
+
+	sub := ms.Subscribe("ABCD1234")
+	ms.Push("ABCD1234", task.StateJob, "1")
+	ms.Push("ABCD1234", task.PlayerJob, "{}")
+
+	select {
+	case <-sub.Notify():
+	default:
+		t.Fatal("Subscriber was not notified of a pushed job")
+	}
+
+	j, err := ms.Pop("ABCD1234")
+	if err != nil || j.JobType != task.StateJob || j.Payload.(string) != "1" {
+		t.Error("Jobs should be popped in the order they were pushed")
+	}
+	j, err = ms.Pop("ABCD1234")
+	if err != nil || j.JobType != task.PlayerJob {
+		t.Error("Second job was not popped correctly")
+	}
+	if _, err = ms.Pop("ABCD1234"); !errors.Is(err, ErrNoJobs) {
+		t.Error("Source should be empty after popping every job")
+	}
+
+	if _, err = ms.Pop("FFFF0000"); !errors.Is(err, ErrNoJobs) {
+		t.Error("Jobs should not leak between connect codes")
+	}
+
+	err = sub.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-sub.Notify(); ok {
+		t.Error("Notify channel should be closed after the subscription is closed")
+	}
+	// pushing with no subscribers, or closing twice, should never panic
+	ms.Push("ABCD1234", task.ConnectionJob, "true")
+	_ = sub.Close()
+}
+
+func TestMemorySource_SubscribeAfterPush(t *testing.T) {
+	ms := NewMemorySource()
+	ms.Push("ABCD1234", task.ConnectionJob, "true")
+
+	sub := ms.Subscribe("ABCD1234")
+	defer sub.Close()
+	select {
+	case <-sub.Notify():
+	default:
+		t.Error("Subscribing with pending jobs should notify immediately")
+	}
+
+	ms.Ack("ABCD1234")
+	if ms.Acks("ABCD1234") != 1 {
+		t.Error("Ack was not recorded")
+	}
+}
+
+func TestMemorySource_SequenceAndMalformed(t *testing.T) {
+	ms := NewMemorySource()
+	ms.Push("ABCD1234", task.StateJob, "1")
+	ms.PushRaw("ABCD1234", "{garbage")
+	ms.Push("ABCD1234", task.StateJob, "2")
+
+	j, err := ms.Pop("ABCD1234")
+	if err != nil || j.Sequence != 1 {
+		t.Error("Pushed jobs should be sequenced starting at 1")
+	}
+	j, err = ms.Pop("ABCD1234")
+	if !errors.Is(err, ErrMalformed) || j.Raw != "{garbage" {
+		t.Error("Malformed jobs should be returned with ErrMalformed and the raw job")
+	}
+	j, err = ms.Pop("ABCD1234")
+	if err != nil || j.Sequence != 2 {
+		t.Error("Raw jobs should not consume a sequence number")
+	}
+}
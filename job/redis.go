@@ -0,0 +1,69 @@
+package job
+
+import (
+	"context"
+	"errors"
+
+	"github.com/automuteus/utils/pkg/rediskey"
+	"github.com/automuteus/utils/pkg/task"
+	"github.com/go-redis/redis/v8"
+)
+
+var ctx = context.Background()
+
+// RedisSource is the Source used in production; jobs are pushed by Galactus onto a Redis list, with a pub/sub
+// notification sent for every push
+type RedisSource struct {
+	client *redis.Client
+}
+
+func NewRedisSource(client *redis.Client) *RedisSource {
+	return &RedisSource{client: client}
+}
+
+func (rs *RedisSource) Subscribe(connectCode string) Subscription {
+	sub := &redisSubscription{
+		pubsub: task.Subscribe(ctx, rs.client, connectCode),
+		notify: make(chan struct{}, 1),
+	}
+	go sub.forward()
+	return sub
+}
+
+func (rs *RedisSource) Pop(connectCode string) (Job, error) {
+	// same list as task.PopJob, but we need the raw string in case the job is malformed
+	str, err := rs.client.LPop(ctx, rediskey.JobNamespace+connectCode).Result()
+	if errors.Is(err, redis.Nil) {
+		return Job{}, ErrNoJobs
+	} else if err != nil {
+		return Job{}, err
+	}
+	return Parse(str)
+}
+
+func (rs *RedisSource) Ack(connectCode string) {
+	task.Ack(ctx, rs.client, connectCode)
+}
+
+type redisSubscription struct {
+	pubsub *redis.PubSub
+	notify chan struct{}
+}
+
+// forward translates the Redis pub/sub messages into plain notifications, until the pub/sub is closed
+func (sub *redisSubscription) forward() {
+	defer close(sub.notify)
+	for msg := range sub.pubsub.Channel() {
+		if msg != nil {
+			signal(sub.notify)
+		}
+	}
+}
+
+func (sub *redisSubscription) Notify() <-chan struct{} {
+	return sub.notify
+}
+
+func (sub *redisSubscription) Close() error {
+	return sub.pubsub.Close()
+}
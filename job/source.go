@@ -0,0 +1,76 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/automuteus/utils/pkg/task"
+)
+
+// ErrNoJobs is returned by Pop when there are no pending jobs for a game
+var ErrNoJobs = errors.New("no jobs available")
+
+// ErrMalformed is returned (wrapped) by Pop when a job was removed from the source, but couldn't be parsed
+var ErrMalformed = errors.New("malformed job")
+
+// Job is a task.Job as received from a Source
+type Job struct {
+	task.Job
+
+	// Sequence is the number assigned to the job by the producer, increasing by 1 for every job pushed for a connect
+	// code. Zero if the producer doesn't sequence its jobs
+	Sequence int64 `json:"seq,omitempty"`
+
+	// Session identifies the capture connection the job came from, as assigned by the producer. Empty if the producer
+	// doesn't tell captures apart
+	Session string `json:"session,omitempty"`
+
+	// Raw is the job exactly as it was received, so it can be dead-lettered if it fails
+	Raw string `json:"-"`
+}
+
+// Parse converts a raw job (as pushed onto the source) into a Job. On failure, the returned Job still carries Raw
+func Parse(raw string) (Job, error) {
+	j := Job{}
+	err := json.Unmarshal([]byte(raw), &j)
+	j.Raw = raw
+	if err != nil {
+		return j, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if _, ok := j.Payload.(string); !ok {
+		return j, fmt.Errorf("%w: payload is %T, not a string", ErrMalformed, j.Payload)
+	}
+	return j, nil
+}
+
+// Source delivers capture jobs for a game (identified by its connect code) to the event handler.
+// Implementations must be safe for concurrent use by multiple games at once
+type Source interface {
+	// Subscribe starts listening for new jobs pushed for the connect code
+	Subscribe(connectCode string) Subscription
+
+	// Pop removes and returns the oldest pending job, or ErrNoJobs if there are none.
+	// Jobs that can't be parsed are still removed, and returned alongside an ErrMalformed error
+	Pop(connectCode string) (Job, error)
+
+	// Ack indicates to whatever is pushing jobs that we're online and ready to start processing them
+	Ack(connectCode string)
+}
+
+// Subscription notifies a listener that one or more jobs may be available to Pop
+type Subscription interface {
+	// Notify receives a value whenever new jobs are pushed. It is closed when the subscription ends
+	Notify() <-chan struct{}
+
+	Close() error
+}
+
+// signal performs a non-blocking send on a notify channel (buffered with size 1). Pending notifications are coalesced,
+// because the listener always pops every available job when it wakes up
+func signal(notify chan struct{}) {
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package job
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/automuteus/utils/pkg/task"
+)
+
+// HistorySize is how many processed jobs (and separately, how many failures) are kept for each game
+const HistorySize = 20
+
+// maxRecordPayload is how much of a job's payload is kept in its history record
+const maxRecordPayload = 200
+
+type Status string
+
+const (
+	StatusProcessed Status = "processed"
+	// StatusGap is a job that was processed, but one or more jobs before it never arrived
+	StatusGap       Status = "gap"
+	StatusDuplicate Status = "duplicate"
+	StatusFailed    Status = "failed"
+)
+
+var TypeNames = map[task.JobType]string{
+	task.ConnectionJob: "connection",
+	task.LobbyJob:      "lobby",
+	task.StateJob:      "state",
+	task.PlayerJob:     "player",
+	task.GameOverJob:   "gameover",
+}
+
+func TypeName(jobType task.JobType) string {
+	if name, ok := TypeNames[jobType]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", jobType)
+}
+
+// HistoryKey is the Redis list of the most recent jobs handled for a game
+func HistoryKey(connectCode string) string {
+	return "automuteus:jobs:history:" + connectCode
+}
+
+// DeadLetterKey is the Redis list of the jobs for a game that failed to parse or process
+func DeadLetterKey(connectCode string) string {
+	return "automuteus:jobs:deadletter:" + connectCode
+}
+
+// Record is a summary of how a single job was handled, stored for debugging
+type Record struct {
+	TimeUnix int64        `json:"time"`
+	Type     task.JobType `json:"type"`
+	Sequence int64        `json:"seq,omitempty"`
+	Status   Status       `json:"status"`
+	Payload  string       `json:"payload"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// NewRecord summarizes a job. Failed jobs keep the full raw job so they can be inspected or replayed
+func NewRecord(j Job, status Status, err error) Record {
+	payload, _ := j.Payload.(string)
+	if status == StatusFailed {
+		payload = j.Raw
+	} else if len(payload) > maxRecordPayload {
+		// don't cut a multi-byte character in half
+		end := maxRecordPayload
+		for end > 0 && !utf8.RuneStart(payload[end]) {
+			end--
+		}
+		payload = payload[:end] + "…"
+	}
+	r := Record{
+		TimeUnix: time.Now().Unix(),
+		Type:     j.JobType,
+		Sequence: j.Sequence,
+		Status:   status,
+		Payload:  payload,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func (r Record) String() string {
+	seq := "-"
+	if r.Sequence > 0 {
+		seq = fmt.Sprintf("#%d", r.Sequence)
+	}
+	str := fmt.Sprintf("%s %s %s %s", time.Unix(r.TimeUnix, 0).UTC().Format("15:04:05"), seq, TypeName(r.Type), r.Status)
+	if r.Error != "" {
+		str += ": " + r.Error
+	}
+	return str
+}
+
+// Tracker follows the sequence numbers of the jobs for a single connect code, to detect duplicates and gaps.
+// It is not safe for concurrent use; each game's subscription worker owns its own Tracker
+type Tracker struct {
+	last int64
+}
+
+// Reset forgets the sequence, for when the capture reconnects and its sequence starts over
+func (t *Tracker) Reset() {
+	t.last = 0
+}
+
+// Check returns how a job with this sequence number should be treated, and records it as seen (unless it's a duplicate).
+// Unsequenced jobs (sequence 0) are always processed
+func (t *Tracker) Check(sequence int64) (Status, int64) {
+	if sequence <= 0 {
+		return StatusProcessed, 0
+	}
+	// the first sequenced job we see is the baseline; we might have (re)subscribed to a game in progress.
+	// A sequence that starts over at 1 is a producer that restarted, not a duplicate
+	if t.last == 0 || (sequence == 1 && t.last > 1) {
+		t.last = sequence
+		return StatusProcessed, 0
+	}
+	if sequence <= t.last {
+		return StatusDuplicate, 0
+	}
+	missed := sequence - t.last - 1
+	t.last = sequence
+	if missed > 0 {
+		return StatusGap, missed
+	}
+	return StatusProcessed, 0
+}
@@ -0,0 +1,87 @@
+package job
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func TestTracker_Check(t *testing.T) {
+	tracker := Tracker{}
+	if status, _ := tracker.Check(0); status != StatusProcessed {
+		t.Error("Unsequenced jobs should always be processed")
+	}
+	if status, _ := tracker.Check(5); status != StatusProcessed {
+		t.Error("The first sequenced job should be processed as the baseline")
+	}
+	if status, _ := tracker.Check(6); status != StatusProcessed {
+		t.Error("The next job in the sequence should be processed")
+	}
+	if status, _ := tracker.Check(6); status != StatusDuplicate {
+		t.Error("Repeated sequence numbers should be detected as duplicates")
+	}
+	if status, _ := tracker.Check(3); status != StatusDuplicate {
+		t.Error("Sequence numbers before the last processed job should be detected as duplicates")
+	}
+	if status, missed := tracker.Check(9); status != StatusGap || missed != 2 {
+		t.Errorf("Expected a gap of 2 missed jobs, got %s with %d missed", status, missed)
+	}
+	if status, _ := tracker.Check(10); status != StatusProcessed {
+		t.Error("Jobs after a gap should be processed normally")
+	}
+	if status, _ := tracker.Check(1); status != StatusProcessed {
+		t.Error("A sequence that starts over should be processed as the new baseline")
+	}
+	if status, _ := tracker.Check(2); status != StatusProcessed {
+		t.Error("Jobs after the sequence started over should be processed")
+	}
+	tracker.Reset()
+	if status, _ := tracker.Check(2); status != StatusProcessed {
+		t.Error("The first job after a reset should be processed as the baseline")
+	}
+}
+
+func TestParse(t *testing.T) {
+	j, err := Parse(`{"type":3,"payload":"{}","seq":4}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.JobType != task.PlayerJob || j.Sequence != 4 || j.Payload.(string) != "{}" {
+		t.Error("Job was not parsed correctly")
+	}
+
+	j, err = Parse(`{"type":2,"payload":"1"}`)
+	if err != nil || j.Sequence != 0 {
+		t.Error("Jobs without a sequence number should still parse")
+	}
+
+	j, err = Parse(`not json`)
+	if !errors.Is(err, ErrMalformed) || j.Raw != "not json" {
+		t.Error("Malformed jobs should return ErrMalformed and keep the raw job")
+	}
+
+	_, err = Parse(`{"type":2,"payload":1}`)
+	if !errors.Is(err, ErrMalformed) {
+		t.Error("Jobs with non-string payloads should be malformed")
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	j, _ := Parse(`{"type":2,"payload":"1","seq":7}`)
+	r := NewRecord(j, StatusFailed, errors.New("bad phase"))
+	if r.Payload != j.Raw || r.Error != "bad phase" {
+		t.Error("Failed records should keep the raw job and the error")
+	}
+	if str := r.String(); !strings.Contains(str, "#7 state failed: bad phase") {
+		t.Errorf("Unexpected record string %s", str)
+	}
+
+	j.Payload = strings.Repeat("a", maxRecordPayload-1) + "é"
+	r = NewRecord(j, StatusProcessed, nil)
+	if !utf8.ValidString(r.Payload) || r.Payload != strings.Repeat("a", maxRecordPayload-1)+"…" {
+		t.Errorf("Long payloads should be truncated on a character boundary, got %q", r.Payload)
+	}
+}
@@ -0,0 +1,279 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is how important a log line is. The values match log/slog's, so they can be swapped later
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO", "":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q; use debug, info, warn or error", s)
+}
+
+// the fields that are added to every line about a guild, game or shard
+const (
+	GuildKey       = "guild"
+	ConnectCodeKey = "connect_code"
+	MatchIDKey     = "match_id"
+	ShardKey       = "shard"
+)
+
+// badKey is used for a value that isn't preceded by a key
+const badKey = "!BADKEY"
+
+type attr struct {
+	key   string
+	value interface{}
+}
+
+// output is shared by a logger and everything derived from it with With
+type output struct {
+	mu    sync.Mutex
+	w     io.Writer
+	json  bool
+	level Level
+	now   func() time.Time
+
+	debugMu     sync.RWMutex
+	debugGuilds map[string]struct{}
+}
+
+// Logger writes leveled lines with key-value fields, as logfmt or JSON
+type Logger struct {
+	out   *output
+	attrs []attr
+	// guildID is the GuildKey field, if there is one, so debug lines can be enabled for a single guild
+	guildID string
+}
+
+func New(w io.Writer, json bool, level Level) *Logger {
+	return &Logger{
+		out: &output{
+			w:     w,
+			json:  json,
+			level: level,
+			now:   time.Now,
+		},
+	}
+}
+
+var defaultLogger = New(os.Stderr, false, LevelInfo)
+
+// Default is the logger set with SetDefault
+func Default() *Logger {
+	return defaultLogger
+}
+
+// SetDefault should only be called at startup, before anything logs
+func SetDefault(l *Logger) {
+	defaultLogger = l
+}
+
+// With is a logger that adds the key-value pairs to every line
+func (l *Logger) With(args ...interface{}) *Logger {
+	attrs := toAttrs(args)
+	child := &Logger{
+		out:     l.out,
+		attrs:   append(append(make([]attr, 0, len(l.attrs)+len(attrs)), l.attrs...), attrs...),
+		guildID: l.guildID,
+	}
+	for _, a := range attrs {
+		if a.key == GuildKey {
+			child.guildID = fmt.Sprint(a.value)
+		}
+	}
+	return child
+}
+
+// SetDebugGuilds logs debug lines for these guilds, whatever the level is
+func (l *Logger) SetDebugGuilds(guildIDs []string) {
+	guilds := make(map[string]struct{}, len(guildIDs))
+	for _, id := range guildIDs {
+		guilds[id] = struct{}{}
+	}
+	l.out.debugMu.Lock()
+	l.out.debugGuilds = guilds
+	l.out.debugMu.Unlock()
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	if level >= l.out.level {
+		return true
+	}
+	if l.guildID == "" {
+		return false
+	}
+	l.out.debugMu.RLock()
+	defer l.out.debugMu.RUnlock()
+	_, ok := l.out.debugGuilds[l.guildID]
+	return ok
+}
+
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(LevelDebug, msg, args...)
+}
+
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(LevelInfo, msg, args...)
+}
+
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(LevelWarn, msg, args...)
+}
+
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(LevelError, msg, args...)
+}
+
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	attrs := append(append(make([]attr, 0, len(l.attrs)+len(args)/2), l.attrs...), toAttrs(args)...)
+	var line []byte
+	if l.out.json {
+		line = l.out.jsonLine(level, msg, attrs)
+	} else {
+		line = l.out.textLine(level, msg, attrs)
+	}
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	// there's nowhere left to report a failed write
+	_, _ = l.out.w.Write(line)
+}
+
+// Writer turns every write into a line at the level, so the standard library's log package can write to the logger
+func (l *Logger) Writer(level Level) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		l.Log(level, strings.TrimRight(string(p), "\n"))
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func toAttrs(args []interface{}) []attr {
+	attrs := make([]attr, 0, (len(args)+1)/2)
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			attrs = append(attrs, attr{badKey, args[0]})
+			args = args[1:]
+			continue
+		}
+		attrs = append(attrs, attr{key, args[1]})
+		args = args[2:]
+	}
+	return attrs
+}
+
+func (o *output) textLine(level Level, msg string, attrs []attr) []byte {
+	buf := strings.Builder{}
+	buf.WriteString("time=")
+	buf.WriteString(o.now().Format(time.RFC3339))
+	buf.WriteString(" level=")
+	buf.WriteString(level.String())
+	buf.WriteString(" msg=")
+	buf.WriteString(quote(msg))
+	for _, a := range attrs {
+		buf.WriteByte(' ')
+		buf.WriteString(a.key)
+		buf.WriteByte('=')
+		buf.WriteString(quote(valueString(a.value)))
+	}
+	buf.WriteByte('\n')
+	return []byte(buf.String())
+}
+
+func (o *output) jsonLine(level Level, msg string, attrs []attr) []byte {
+	buf := strings.Builder{}
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, o.now().Format(time.RFC3339))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, msg)
+	for _, a := range attrs {
+		buf.WriteByte(',')
+		writeJSON(&buf, a.key)
+		buf.WriteByte(':')
+		if err, ok := a.value.(error); ok {
+			writeJSON(&buf, err.Error())
+		} else {
+			writeJSON(&buf, a.value)
+		}
+	}
+	buf.WriteString("}\n")
+	return []byte(buf.String())
+}
+
+func writeJSON(buf *strings.Builder, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// quote quotes values that couldn't be told apart from the next field otherwise
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\n\t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLogger(buf *bytes.Buffer, json bool, level Level) *Logger {
+	l := New(buf, json, level)
+	l.out.now = func() time.Time {
+		return time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	return l
+}
+
+func TestText(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := testLogger(&buf, false, LevelInfo).With(ShardKey, 2)
+	l.With(GuildKey, "123", ConnectCodeKey, "ABCDEFGH").Warn("game ended", "reason", "timed out", "err", errors.New("oops"), "odd")
+
+	expected := `time=2022-01-02T03:04:05Z level=WARN msg="game ended" shard=2 guild=123 connect_code=ABCDEFGH reason="timed out" err=oops !BADKEY=odd` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%sgot\n%s", expected, buf.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := testLogger(&buf, true, LevelInfo)
+	l.With(MatchIDKey, int64(42)).Error("couldn't save", "err", errors.New("oops"))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["level"] != "ERROR" || line["msg"] != "couldn't save" || line[MatchIDKey] != float64(42) || line["err"] != "oops" {
+		t.Errorf("unexpected line %s", buf.String())
+	}
+}
+
+func TestDebugGuilds(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := testLogger(&buf, false, LevelInfo)
+	l.SetDebugGuilds([]string{"123"})
+
+	l.Debug("no guild")
+	l.With(GuildKey, "456").Debug("other guild")
+	if buf.Len() > 0 {
+		t.Errorf("expected debug lines to be dropped, got %s", buf.String())
+	}
+	l.With(GuildKey, "123").With(ConnectCodeKey, "ABCDEFGH").Debug("debugged guild")
+	if !strings.Contains(buf.String(), "debugged guild") {
+		t.Error("expected debug lines for the guild to be logged")
+	}
+
+	buf.Reset()
+	l.SetDebugGuilds(nil)
+	l.With(GuildKey, "123").Debug("debugged guild")
+	if buf.Len() > 0 {
+		t.Errorf("expected debug lines to be dropped again, got %s", buf.String())
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := testLogger(&buf, false, LevelInfo)
+	_, _ = l.Writer(LevelInfo).Write([]byte("from the log package\n"))
+	if !strings.HasSuffix(buf.String(), `msg="from the log package"`+"\n") {
+		t.Errorf("unexpected line %s", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, expected := range map[string]Level{"debug": LevelDebug, "": LevelInfo, "Warning": LevelWarn, "ERROR": LevelError} {
+		if level, err := ParseLevel(s); err != nil || level != expected {
+			t.Errorf("%q: expected %s, got %s %v", s, expected, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.txt")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "third\n",
+		path + ".1": "second\n",
+		path + ".2": "first\n",
+	}
+	for p, content := range expected {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: expected %q, got %q", p, content, b)
+		}
+	}
+	// the previous run's log was the oldest
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("expected only 2 backups to be kept")
+	}
+}
@@ -2,18 +2,22 @@ package main
 
 import (
 	"errors"
+	"flag"
+	"fmt"
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/config"
 	"github.com/automuteus/automuteus/discord/command"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/utils/pkg/locale"
 	storage2 "github.com/automuteus/utils/pkg/storage"
 	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
 	"io"
 	"log"
 	"math/rand"
 	"os"
 	"os/signal"
 	"path"
-	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -28,209 +32,240 @@ var (
 	date    = "unknown"
 )
 
-const DefaultURL = "http://localhost:8123"
-
-type registeredCommand struct {
-	GuildID            string
-	ApplicationCommand *discordgo.ApplicationCommand
-}
+var (
+	configPath       = flag.String("config", os.Getenv("AUTOMUTEUS_CONFIG"), "TOML or YAML config file; environment variables override it")
+	printConfig      = flag.Bool("print-config", false, "print the config with its secrets redacted, then exit")
+	syncCommandsOnly = flag.Bool("sync-commands", false, "sync the slash commands with Discord, then exit")
+	dryRun           = flag.Bool("dry-run", false, "with --sync-commands, only report which commands would change")
+	maintenanceMode  = flag.String("maintenance", "", "turn maintenance mode \"on\" or \"off\" for all shards, then exit")
+	maintenanceETA   = flag.Duration("maintenance-eta", 0, "with --maintenance on, how long maintenance should take")
+)
 
 func main() {
+	flag.Parse()
 	// seed the rand generator (used for making connection codes)
 	rand.Seed(time.Now().Unix())
 	err := discordMainWrapper()
 	if err != nil {
-		log.Println("Program exited with the following error:")
-		log.Println(err)
+		logging.Default().Error("Program exited with an error", "error", err)
 		return
 	}
 }
 
 func discordMainWrapper() error {
-	var isOfficial = os.Getenv("AUTOMUTEUS_OFFICIAL") != ""
-
-	discordToken := os.Getenv("DISCORD_BOT_TOKEN")
-	if discordToken == "" {
-		return errors.New("no DISCORD_BOT_TOKEN provided")
-	}
-	logPath := os.Getenv("LOG_PATH")
-	if logPath == "" {
-		logPath = "./"
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
 	}
-
-	logEntry := os.Getenv("DISABLE_LOG_FILE")
-	if logEntry == "" {
-		file, err := os.Create(path.Join(logPath, "logs.txt"))
+	if *printConfig {
+		out, err := cfg.TOML()
 		if err != nil {
 			return err
 		}
-		mw := io.MultiWriter(os.Stdout, file)
-		log.SetOutput(mw)
+		fmt.Print(out)
+		return cfg.Validate()
 	}
 
-	emojiGuildID := os.Getenv("EMOJI_GUILD_ID")
+	// only needs Redis, not a connection to Discord
+	if *maintenanceMode != "" {
+		return setMaintenance(cfg.Redis, *maintenanceMode, *maintenanceETA)
+	}
 
-	log.Println(version + "-" + commit)
+	// empty string entry = global
+	slashCommandGuildIds := []string{""}
+	if len(cfg.Discord.SlashCommandGuildIDs) > 0 {
+		slashCommandGuildIds = cfg.Discord.SlashCommandGuildIDs
+	}
 
-	if os.Getenv("WORKER_BOT_TOKENS") != "" {
-		log.Println("WORKER_BOT_TOKENS is now a variable used by Galactus, not AutoMuteUs!")
-		log.Fatal("Move WORKER_BOT_TOKENS to Galactus' config, then try again")
+	if *syncCommandsOnly {
+		if cfg.Discord.Token == "" {
+			return errors.New("no DISCORD_BOT_TOKEN provided")
+		}
+		s, err := discordgo.New("Bot " + cfg.Discord.Token)
+		if err != nil {
+			return err
+		}
+		app, err := s.User("@me")
+		if err != nil {
+			return err
+		}
+		// the commands are localized from the same bundle as the running bot, or the two keep overwriting each other
+		locale.InitLang(cfg.Locale.Path, cfg.Locale.Lang)
+		// the owner command is only registered in the admin guild
+		return syncCommands(s, app.ID, slashCommandGuildIds, cfg.Discord.AdminGuildID, *dryRun)
 	}
 
-	numShardsStr := os.Getenv("NUM_SHARDS")
-	numShards, err := strconv.Atoi(numShardsStr)
+	err = cfg.Validate()
 	if err != nil {
-		log.Println("No NUM_SHARDS specified; defaulting to 1")
-		numShards = 1
+		return err
 	}
 
-	shardIDStr := os.Getenv("SHARD_ID")
-	shardID, err := strconv.Atoi(shardIDStr)
-	if shardID >= numShards {
-		return errors.New("you specified a shardID higher than or equal to the total number of shards")
-	}
+	err = setupLogging(cfg)
 	if err != nil {
-		log.Println("No SHARD_ID specified; defaulting to 0")
-		shardID = 0
+		return err
 	}
 
-	url := os.Getenv("HOST")
-	if url == "" {
-		log.Printf("[Info] No valid HOST provided. Defaulting to %s\n", DefaultURL)
-		url = DefaultURL
+	logging.Default().Info("Starting AutoMuteUs", "version", version, "commit", commit)
+
+	if os.Getenv("WORKER_BOT_TOKENS") != "" {
+		return errors.New("WORKER_BOT_TOKENS is now a variable used by Galactus, not AutoMuteUs! Move it to Galactus' config, then try again")
 	}
 
 	var redisClient discord.RedisInterface
 	var storageInterface storage.StorageInterface
 
-	redisAddr := os.Getenv("REDIS_ADDR")
-	redisPassword := os.Getenv("REDIS_PASS")
-	if redisAddr != "" {
-		err := redisClient.Init(storage.RedisParameters{
-			Addr:     redisAddr,
-			Username: "",
-			Password: redisPassword,
-		})
-		if err != nil {
-			log.Println(err)
-		}
-		err = storageInterface.Init(storage.RedisParameters{
-			Addr:     redisAddr,
-			Username: "",
-			Password: redisPassword,
-		})
-		if err != nil {
-			log.Println(err)
-		}
-	} else {
-		return errors.New("no REDIS_ADDR specified; exiting")
+	err = redisClient.Init(storage.RedisParameters{
+		Addr:     cfg.Redis.Addr,
+		Username: "",
+		Password: cfg.Redis.Password,
+	})
+	if err != nil {
+		logging.Default().Error("Error connecting to Redis", "error", err)
 	}
-
-	galactusAddr := os.Getenv("GALACTUS_ADDR")
-	if galactusAddr == "" {
-		return errors.New("no GALACTUS_ADDR specified; exiting")
+	err = storageInterface.Init(storage.RedisParameters{
+		Addr:     cfg.Redis.Addr,
+		Username: "",
+		Password: cfg.Redis.Password,
+	})
+	if err != nil {
+		logging.Default().Error("Error connecting to Redis", "error", err)
 	}
 
-	galactusClient, err := discord.NewGalactusClient(galactusAddr)
+	galactusClient, err := discord.NewGalactusClient(cfg.Galactus.Addr)
 	if err != nil {
-		log.Println("Error connecting to Galactus!")
+		logging.Default().Error("Error connecting to Galactus", "error", err)
 		return err
 	}
 
-	locale.InitLang(os.Getenv("LOCALE_PATH"), os.Getenv("BOT_LANG"))
+	locale.InitLang(cfg.Locale.Path, cfg.Locale.Lang)
 
 	psql := storage2.PsqlInterface{}
-	pAddr := os.Getenv("POSTGRES_ADDR")
-	if pAddr == "" {
-		return errors.New("no POSTGRES_ADDR specified; exiting")
-	}
-
-	pUser := os.Getenv("POSTGRES_USER")
-	if pUser == "" {
-		return errors.New("no POSTGRES_USER specified; exiting")
-	}
-
-	pPass := os.Getenv("POSTGRES_PASS")
-	if pPass == "" {
-		return errors.New("no POSTGRES_PASS specified; exiting")
-	}
-
-	err = psql.Init(storage2.ConstructPsqlConnectURL(pAddr, pUser, pPass))
+	err = psql.Init(storage2.ConstructPsqlConnectURL(cfg.Postgres.Addr, cfg.Postgres.User, cfg.Postgres.Password))
 	if err != nil {
 		return err
 	}
 
-	if !isOfficial {
+	if !cfg.Official {
 		go func() {
 			err := psql.LoadAndExecFromFile("./storage/postgres.sql")
 			if err != nil {
-				log.Println("Exiting with fatal error when attempting to execute postgres.sql:")
-				log.Fatal(err)
+				logging.Default().Error("Exiting with fatal error when attempting to execute postgres.sql", "error", err)
+				os.Exit(1)
 			}
 		}()
 	}
 
-	log.Println("Bot is now running.  Press CTRL-C to exit.")
+	logging.Default().Info("Bot is now running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	topGGToken := os.Getenv("TOP_GG_TOKEN")
 
-	bot := discord.MakeAndStartBot(version, commit, discordToken, topGGToken, url, emojiGuildID, numShards, shardID, &redisClient, &storageInterface, &psql, galactusClient, logPath)
+	bot := discord.MakeAndStartBot(version, commit, cfg, &redisClient, &storageInterface, &psql, galactusClient)
 	if bot == nil {
-		log.Fatal("bot failed to initialize; did you provide a valid Discord Bot Token?")
+		return errors.New("bot failed to initialize; did you provide a valid Discord Bot Token?")
 	}
 
-	// empty string entry = global
-	slashCommandGuildIds := []string{""}
-	slashCommandGuildIdStr := strings.ReplaceAll(os.Getenv("SLASH_COMMAND_GUILD_IDS"), " ", "")
-	if slashCommandGuildIdStr != "" {
-		slashCommandGuildIds = strings.Split(slashCommandGuildIdStr, ",")
-	}
-
-	var registeredCommands []registeredCommand
-	if !isOfficial || shardID == 0 {
-		for _, guild := range slashCommandGuildIds {
-			for _, v := range command.All {
-				if guild == "" {
-					log.Printf("Registering command %s GLOBALLY\n", v.Name)
-				} else {
-					log.Printf("Registering command %s in guild %s\n", v.Name, guild)
-				}
-
-				id, err := bot.PrimarySession.ApplicationCommandCreate(bot.PrimarySession.State.User.ID, guild, v)
-				if err != nil {
-					log.Panicf("Cannot create command: %v", err)
-				} else {
-					registeredCommands = append(registeredCommands, registeredCommand{
-						GuildID:            guild,
-						ApplicationCommand: id,
-					})
-				}
-			}
+	if !cfg.Official || cfg.Discord.ShardID == 0 {
+		err = syncCommands(bot.PrimarySession, bot.PrimarySession.State.User.ID, slashCommandGuildIds, cfg.Discord.AdminGuildID, false)
+		if err != nil {
+			log.Panicf("Cannot sync commands: %v", err)
 		}
-		log.Println("Finishing registering all commands!")
 	}
 
 	<-sc
-	log.Printf("Received Sigterm or Kill signal. Bot will terminate in 1 second")
+	logging.Default().Info("Received Sigterm or Kill signal. Bot will terminate in 1 second")
 	time.Sleep(time.Second)
 
-	if !isOfficial {
-		log.Println("Deleting slash commands")
-		for _, v := range registeredCommands {
-			if v.GuildID == "" {
-				log.Printf("Deleting command %s GLOBALLY\n", v.ApplicationCommand.Name)
-			} else {
-				log.Printf("Deleting command %s on guild %s\n", v.ApplicationCommand.Name, v.GuildID)
-			}
-			err = bot.PrimarySession.ApplicationCommandDelete(v.ApplicationCommand.ApplicationID, v.GuildID, v.ApplicationCommand.ID)
-			if err != nil {
-				log.Println(err)
-			}
+	bot.Close()
+	return nil
+}
+
+// syncCommands brings the commands registered in each guild (or globally, for an empty guild ID) up to date, and the
+// owner command in the admin guild
+func syncCommands(s *discordgo.Session, appID string, guildIDs []string, adminGuildID string, dryRun bool) error {
+	cmds := make(map[string][]*discordgo.ApplicationCommand)
+	var order []string
+	for _, guild := range guildIDs {
+		cmds[guild] = command.Registered()
+		order = append(order, guild)
+	}
+	if adminGuildID != "" {
+		if _, ok := cmds[adminGuildID]; !ok {
+			order = append(order, adminGuildID)
 		}
-		log.Println("Finished deleting all commands")
+		cmds[adminGuildID] = append(cmds[adminGuildID], &command.Owner)
 	}
 
-	bot.Close()
+	for _, guild := range order {
+		where := "GLOBALLY"
+		if guild != "" {
+			where = "in guild " + guild
+		}
+		report, err := command.Sync(s, appID, guild, cmds[guild], dryRun)
+		if err != nil {
+			return err
+		}
+		switch {
+		case report.InSync():
+			logging.Default().Info("Commands are up to date", "where", where)
+		case dryRun:
+			logging.Default().Info("Would update commands", "where", where, "changes", report)
+		default:
+			logging.Default().Info("Updated commands", "where", where, "changes", report)
+		}
+	}
+	return nil
+}
+
+// setupLogging makes the default logger, which the log package writes to as well. Every line has the shard ID
+func setupLogging(cfg *config.Config) error {
+	level, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if !cfg.Log.DisableFile {
+		file, err := logging.OpenRotatingFile(path.Join(cfg.Log.Path, "logs.txt"), int64(cfg.Log.MaxSizeMB)<<20, cfg.Log.MaxBackups)
+		if err != nil {
+			return err
+		}
+		w = io.MultiWriter(os.Stdout, file)
+	}
+	logger := logging.New(w, cfg.Log.JSON, level).With(logging.ShardKey, cfg.Discord.ShardID)
+	logger.SetDebugGuilds(cfg.Log.DebugGuildIDs)
+	logging.SetDefault(logger)
+
+	// what's left on the log package is mostly errors, so it mustn't be dropped below the info level
+	log.SetFlags(0)
+	log.SetOutput(logger.Writer(logging.LevelWarn))
+	return nil
+}
+
+// setMaintenance turns maintenance mode on or off in Redis, where every shard will notice it
+func setMaintenance(redisConfig config.RedisConfig, mode string, eta time.Duration) error {
+	if mode != "on" && mode != "off" {
+		return fmt.Errorf("--maintenance must be \"on\" or \"off\", not %q", mode)
+	}
+	if redisConfig.Addr == "" {
+		return errors.New("no REDIS_ADDR specified; exiting")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisConfig.Addr,
+		Password: redisConfig.Password,
+	})
+	defer client.Close()
+
+	var etaTime time.Time
+	if eta > 0 {
+		etaTime = time.Now().Add(eta)
+	}
+	err := redis_common.SetMaintenance(client, mode == "on", etaTime)
+	if err != nil {
+		return err
+	}
+	if mode == "on" && eta > 0 {
+		logging.Default().Info("Maintenance mode is on", "until", etaTime.Format(time.RFC1123))
+	} else {
+		logging.Default().Info("Maintenance mode is " + mode)
+	}
 	return nil
 }
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailing  = "failing"
+	HealthStarting = "starting"
+)
+
+// Check is a dependency of the bot. If a critical check fails the bot isn't ready; if any other check fails it's only
+// degraded, because games keep working without it
+type Check struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+type HealthReport struct {
+	Status      string                 `json:"status"`
+	ShardID     int                    `json:"shardID"`
+	ShardCount  int                    `json:"shardCount"`
+	Checked     int64                  `json:"checked"`
+	Checks      map[string]CheckResult `json:"checks"`
+	Maintenance MaintenanceStatus      `json:"maintenance"`
+}
+
+// HealthChecker runs the checks at most once per TTL, so probes can't overload the dependencies, and gives each one
+// Timeout to finish. The checks run in the background, so a probe never waits on them or cancels them
+type HealthChecker struct {
+	ShardID    int
+	ShardCount int
+	TTL        time.Duration
+	Timeout    time.Duration
+	Checks     []Check
+
+	mu      sync.Mutex
+	report  HealthReport
+	checked time.Time
+	// refreshed is closed when the checks that are running finish; it's nil if none are
+	refreshed chan struct{}
+	now       func() time.Time
+}
+
+func NewHealthChecker(shardID, shardCount int, checks ...Check) *HealthChecker {
+	return &HealthChecker{
+		ShardID:    shardID,
+		ShardCount: shardCount,
+		TTL:        10 * time.Second,
+		Timeout:    3 * time.Second,
+		Checks:     checks,
+		now:        time.Now,
+	}
+}
+
+// Report is the result of the last checks. If they're older than TTL, they're run again in the background and the old
+// result is returned; only the very first report waits for them
+func (hc *HealthChecker) Report() HealthReport {
+	hc.mu.Lock()
+	first := hc.checked.IsZero()
+	if hc.refreshed == nil && (first || hc.now().Sub(hc.checked) >= hc.TTL) {
+		hc.refreshed = make(chan struct{})
+		go hc.refresh(hc.refreshed)
+	}
+	refreshed := hc.refreshed
+	report := hc.report
+	hc.mu.Unlock()
+
+	if first {
+		<-refreshed
+		hc.mu.Lock()
+		report = hc.report
+		hc.mu.Unlock()
+	}
+	return report
+}
+
+func (hc *HealthChecker) refresh(done chan struct{}) {
+	now := hc.now()
+	results := make([]CheckResult, len(hc.Checks))
+	wg := sync.WaitGroup{}
+	for i, check := range hc.Checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = hc.run(context.Background(), check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status:     HealthOK,
+		ShardID:    hc.ShardID,
+		ShardCount: hc.ShardCount,
+		Checked:    now.Unix(),
+		Checks:     make(map[string]CheckResult, len(hc.Checks)),
+	}
+	for i, check := range hc.Checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status == HealthOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = HealthFailing
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+
+	hc.mu.Lock()
+	hc.report = report
+	hc.checked = now
+	hc.refreshed = nil
+	hc.mu.Unlock()
+	close(done)
+}
+
+func (hc *HealthChecker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{
+		Status:    HealthOK,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = HealthFailing
+		result.Error = err.Error()
+	}
+	return result
+}
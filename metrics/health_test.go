@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerStatus(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("down") }
+
+	tests := []struct {
+		name     string
+		checks   []Check
+		expected string
+	}{
+		{"all ok", []Check{{Name: "redis", Critical: true, Check: ok}, {Name: "postgres", Check: ok}}, HealthOK},
+		{"optional failing", []Check{{Name: "redis", Critical: true, Check: ok}, {Name: "postgres", Check: fail}}, HealthDegraded},
+		{"critical failing", []Check{{Name: "redis", Critical: true, Check: fail}, {Name: "postgres", Check: fail}}, HealthFailing},
+	}
+	for _, tt := range tests {
+		report := NewHealthChecker(1, 2, tt.checks...).Report()
+		if report.Status != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, report.Status)
+		}
+		if report.ShardID != 1 || report.ShardCount != 2 || len(report.Checks) != len(tt.checks) {
+			t.Errorf("%s: unexpected report %+v", tt.name, report)
+		}
+	}
+}
+
+func TestHealthCheckerCache(t *testing.T) {
+	calls := 0
+	hc := NewHealthChecker(0, 1, Check{Name: "redis", Check: func(ctx context.Context) error {
+		calls++
+		return nil
+	}})
+	now := time.Unix(1700000000, 0)
+	hc.now = func() time.Time { return now }
+
+	hc.Report()
+	hc.Report()
+	if calls != 1 {
+		t.Errorf("expected the result to be cached, but the check ran %d times", calls)
+	}
+	checked := now.Unix()
+	now = now.Add(hc.TTL)
+	if report := hc.Report(); report.Checked != checked {
+		t.Errorf("expected the old result while the checks run again, got one from %d", report.Checked)
+	}
+	hc.mu.Lock()
+	refreshed := hc.refreshed
+	hc.mu.Unlock()
+	if refreshed == nil {
+		t.Fatal("expected the checks to run again after the TTL")
+	}
+	<-refreshed
+	if calls != 2 || hc.Report().Checked != now.Unix() {
+		t.Errorf("expected the check to run again after the TTL, but it ran %d times", calls)
+	}
+}
+
+func TestHealthCheckerTimeout(t *testing.T) {
+	hc := NewHealthChecker(0, 1, Check{Name: "galactus", Critical: true, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	hc.Timeout = 10 * time.Millisecond
+
+	result := hc.Report().Checks["galactus"]
+	if result.Status != HealthFailing || result.Error == "" {
+		t.Errorf("expected a check that hangs to fail, got %+v", result)
+	}
+}
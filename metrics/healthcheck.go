@@ -1,14 +1,48 @@
 package metrics
 
 import (
+	"encoding/json"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 )
 
 var GlobalReady = false
 
-func StartHealthCheckServer(port string) {
+// MaintenanceStatus is whether the bot is in maintenance mode, and when it should be over (zero if that isn't known)
+type MaintenanceStatus struct {
+	Enabled bool  `json:"enabled"`
+	ETA     int64 `json:"eta,omitempty"`
+}
+
+// maintenance is the status as of the last time the bot checked
+var maintenance = struct {
+	sync.RWMutex
+	status MaintenanceStatus
+}{}
+
+// SetMaintenance records whether the bot is in maintenance mode for the health check, and when maintenance should be
+// over (zero if that isn't known)
+func SetMaintenance(enabled bool, eta time.Time) {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+	maintenance.status = MaintenanceStatus{Enabled: enabled}
+	if !eta.IsZero() {
+		maintenance.status.ETA = eta.Unix()
+	}
+}
+
+func getMaintenance() MaintenanceStatus {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	return maintenance.status
+}
+
+// StartHealthCheckServer serves the liveness and readiness probes, the results of every health check at /health, and
+// of a single check at /health/{check}
+func StartHealthCheckServer(port string, health *HealthChecker) {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
@@ -17,31 +51,62 @@ func StartHealthCheckServer(port string) {
 	})
 
 	r.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if GlobalReady {
-			resp, err := http.Get("https://discordapp.com/api/v8/gateway")
-			if err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-			defer resp.Body.Close()
-			w.WriteHeader(resp.StatusCode)
-			if resp.StatusCode == http.StatusOK {
-				w.Write([]byte("ready"))
-			} else {
-				w.Write([]byte("unready"))
-			}
-
-			if resp.StatusCode == http.StatusTooManyRequests {
-				w.WriteHeader(http.StatusTooManyRequests)
-				log.Println("I'M BEING RATE-LIMITED BY DISCORD")
-				return
-			}
-		} else {
+		if !GlobalReady {
 			w.WriteHeader(http.StatusTooEarly)
+			return
+		}
+		report := health.Report()
+		if report.Status == HealthFailing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("unready"))
+			return
 		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ready"))
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		report := health.Report()
+		report.Maintenance = getMaintenance()
+		if !GlobalReady {
+			report.Status = HealthStarting
+		}
+		status := http.StatusOK
+		if report.Status == HealthFailing || report.Status == HealthStarting {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+
+	r.HandleFunc("/health/{check}", func(w http.ResponseWriter, r *http.Request) {
+		result, ok := health.Report().Checks[mux.Vars(r)["check"]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		status := http.StatusOK
+		if result.Status != HealthOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, result)
+	})
+
+	// running games aren't affected by maintenance mode, so it doesn't make the bot unready
+	r.HandleFunc("/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, getMaintenance())
 	})
 
 	http.ListenAndServe(":"+port, r)
 }
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	jBytes, err := json.Marshal(v)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jBytes)
+}
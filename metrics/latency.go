@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// interaction types, as labels for the latency metrics
+const (
+	CommandInteraction      = "command"
+	AutocompleteInteraction = "autocomplete"
+	ComponentInteraction    = "component"
+)
+
+var interactionLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "interaction_latency_seconds",
+	Help:    "Time taken to respond to Discord interactions, differentiated by type/command and whether the response was deferred",
+	Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 15},
+}, []string{"type", "command", "deferred"})
+
+// RecordInteractionLatency records how long we took to respond to an interaction. Components are all recorded under
+// one name, since their custom IDs can contain user IDs
+func RecordInteractionLatency(interactionType, command string, deferred bool, latency time.Duration) {
+	interactionLatency.WithLabelValues(interactionType, command, strconv.FormatBool(deferred)).Observe(latency.Seconds())
+}
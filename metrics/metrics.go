@@ -103,6 +103,7 @@ func NewCollector(client *redis.Client, nodeID string) *Collector {
 
 func PrometheusMetricsServer(client *redis.Client, nodeID, port string) error {
 	prometheus.MustRegister(NewCollector(client, nodeID))
+	prometheus.MustRegister(interactionLatency)
 
 	http.Handle("/metrics", promhttp.Handler())
 
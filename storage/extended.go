@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/rediskey"
+	"github.com/go-redis/redis/v8"
+	"log"
+)
+
+// ExtendedGuildSettings are guild settings that aren't (yet) part of settings.GuildSettings, so they're stored
+// under their own key
+type ExtendedGuildSettings struct {
+	CaptureQRCode bool `json:"captureQRCode"`
+	// CommandPermissions maps a command or subcommand ("new", "stats clear") to who may use it, instead of its
+	// default permission level
+	CommandPermissions map[string]*CommandPermission `json:"commandPermissions,omitempty"`
+	// AnnouncementChannelID is where announcements from the bot's owners are posted; they aren't posted if it's empty
+	AnnouncementChannelID string `json:"announcementChannelID"`
+}
+
+// CommandPermission is the roles and users that are allowed to use a command
+type CommandPermission struct {
+	RoleIDs []string `json:"roleIDs,omitempty"`
+	UserIDs []string `json:"userIDs,omitempty"`
+}
+
+// Allows is whether the member has one of the roles, or is one of the users
+func (perm *CommandPermission) Allows(userID string, roleIDs []string) bool {
+	for _, id := range perm.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	for _, id := range perm.RoleIDs {
+		for _, roleID := range roleIDs {
+			if id == roleID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Mentions are the roles and then the users, formatted to mention them
+func (perm *CommandPermission) Mentions() []string {
+	mentions := make([]string, 0, len(perm.RoleIDs)+len(perm.UserIDs))
+	for _, id := range perm.RoleIDs {
+		mentions = append(mentions, "<@&"+id+">")
+	}
+	for _, id := range perm.UserIDs {
+		mentions = append(mentions, discord.MentionByUserID(id))
+	}
+	return mentions
+}
+
+func MakeExtendedGuildSettings() *ExtendedGuildSettings {
+	return &ExtendedGuildSettings{
+		CaptureQRCode: true,
+	}
+}
+
+func extendedGuildSettingsKey(guildID string) string {
+	return "automuteus:settings:extended:guild:" + string(rediskey.HashGuildID(guildID))
+}
+
+func (storageInterface *StorageInterface) GetExtendedGuildSettings(guildID string) *ExtendedGuildSettings {
+	s := MakeExtendedGuildSettings()
+	j, err := storageInterface.client.Get(ctx, extendedGuildSettingsKey(guildID)).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return s
+	case err != nil:
+		log.Println(err)
+		return s
+	default:
+		// unmarshal over the defaults, so settings added later get their default values
+		err := json.Unmarshal([]byte(j), s)
+		if err != nil {
+			log.Println(err)
+			return MakeExtendedGuildSettings()
+		}
+		return s
+	}
+}
+
+func (storageInterface *StorageInterface) SetExtendedGuildSettings(guildID string, ext *ExtendedGuildSettings) error {
+	jBytes, err := json.MarshalIndent(ext, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storageInterface.client.Set(ctx, extendedGuildSettingsKey(guildID), jBytes, 0).Err()
+}
+
+func (storageInterface *StorageInterface) DeleteExtendedGuildSettings(guildID string) error {
+	return storageInterface.client.Del(ctx, extendedGuildSettingsKey(guildID)).Err()
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	storageutils "github.com/automuteus/utils/pkg/storage"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetPersonalConnectCode returns the user's personal connect code, or "" if they haven't opted in
+func GetPersonalConnectCode(psql *storageutils.PsqlInterface, userID string) (string, error) {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	var code string
+	err = psql.Pool.QueryRow(ctx, "SELECT connect_code FROM personal_connect_codes WHERE user_id = $1;", uid).Scan(&code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return code, err
+}
+
+// IsPersonalConnectCode returns true if the code belongs to any user, so it shouldn't be handed out to anyone else
+func IsPersonalConnectCode(psql *storageutils.PsqlInterface, connectCode string) (bool, error) {
+	var exists bool
+	err := psql.Pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM personal_connect_codes WHERE connect_code = $1);", connectCode).Scan(&exists)
+	return exists, err
+}
+
+// SetPersonalConnectCode sets (or replaces, revoking the previous code) the user's personal connect code
+func SetPersonalConnectCode(psql *storageutils.PsqlInterface, userID, connectCode string) error {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = psql.EnsureUserExists(uid)
+	if err != nil {
+		return err
+	}
+	_, err = psql.Pool.Exec(ctx, "INSERT INTO personal_connect_codes VALUES ($1, $2, $3) "+
+		"ON CONFLICT (user_id) DO UPDATE SET connect_code = EXCLUDED.connect_code, created_time = EXCLUDED.created_time;",
+		uid, connectCode, int32(time.Now().Unix()))
+	return err
+}
+
+func DeletePersonalConnectCode(psql *storageutils.PsqlInterface, userID string) error {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = psql.Pool.Exec(ctx, "DELETE FROM personal_connect_codes WHERE user_id = $1;", uid)
+	return err
+}
+
+// NoPreferredColor is the preferred color of a profile without one
+const NoPreferredColor = -1
+
+// UserProfile is the in-game names and color a user usually plays with
+type UserProfile struct {
+	UserID         string
+	InGameNames    []string
+	PreferredColor int
+}
+
+// GetUserProfile returns the user's profile, or nil if they haven't set one
+func GetUserProfile(psql *storageutils.PsqlInterface, userID string) (*UserProfile, error) {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	profile := UserProfile{UserID: userID}
+	var color int16
+	err = psql.Pool.QueryRow(ctx, "SELECT in_game_names, preferred_color FROM user_profiles WHERE user_id = $1;", uid).Scan(&profile.InGameNames, &color)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	profile.PreferredColor = int(color)
+	return &profile, nil
+}
+
+// SetUserProfile sets (or replaces) the user's profile. Names are stored lowercase
+func SetUserProfile(psql *storageutils.PsqlInterface, profile UserProfile) error {
+	uid, err := strconv.ParseUint(profile.UserID, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = psql.EnsureUserExists(uid)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(profile.InGameNames))
+	for i, name := range profile.InGameNames {
+		names[i] = strings.ToLower(name)
+	}
+	_, err = psql.Pool.Exec(ctx, "INSERT INTO user_profiles VALUES ($1, $2, $3) "+
+		"ON CONFLICT (user_id) DO UPDATE SET in_game_names = EXCLUDED.in_game_names, preferred_color = EXCLUDED.preferred_color;",
+		uid, names, int16(profile.PreferredColor))
+	return err
+}
+
+func DeleteUserProfile(psql *storageutils.PsqlInterface, userID string) error {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = psql.Pool.Exec(ctx, "DELETE FROM user_profiles WHERE user_id = $1;", uid)
+	return err
+}
+
+// GetUserProfilesByInGameName returns the profiles of every user who plays as the in-game name
+func GetUserProfilesByInGameName(psql *storageutils.PsqlInterface, name string) ([]UserProfile, error) {
+	rows, err := psql.Pool.Query(ctx, "SELECT user_id, in_game_names, preferred_color FROM user_profiles WHERE in_game_names @> $1;",
+		[]string{strings.ToLower(name)})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []UserProfile
+	for rows.Next() {
+		var uid uint64
+		var color int16
+		var profile UserProfile
+		err = rows.Scan(&uid, &profile.InGameNames, &color)
+		if err != nil {
+			return nil, err
+		}
+		profile.UserID = strconv.FormatUint(uid, 10)
+		profile.PreferredColor = int(color)
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// GetRecentGames returns the guild's most recently finished games whose match ID (CODE:gameID) starts with prefix,
+// most recent first
+func GetRecentGames(psql *storageutils.PsqlInterface, guildID, prefix string, limit int) ([]storageutils.PostgresGame, error) {
+	gid, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := psql.Pool.Query(ctx, "SELECT game_id, connect_code, start_time, win_type, end_time FROM games "+
+		"WHERE guild_id = $1 AND end_time > 0 AND (connect_code || ':' || game_id) ILIKE $2 ORDER BY game_id DESC LIMIT $3;",
+		gid, escapeLike(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []storageutils.PostgresGame
+	for rows.Next() {
+		g := storageutils.PostgresGame{GuildID: gid}
+		err = rows.Scan(&g.GameID, &g.ConnectCode, &g.StartTime, &g.WinType, &g.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+func escapeLike(str string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(str)
+}
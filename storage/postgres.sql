@@ -51,6 +51,22 @@ create table if not exists users_games
     PRIMARY KEY (user_id, game_id)
 );
 
+-- opt-in, stable connect codes for regular hosts, so their capture can reconnect to whichever game they start next
+create table if not exists personal_connect_codes
+(
+    user_id      numeric PRIMARY KEY REFERENCES users ON DELETE CASCADE, --if a user gets deleted, revoke their code
+    connect_code CHAR(8) NOT NULL UNIQUE,
+    created_time integer NOT NULL                                       --2038 problem, but I do not care
+);
+
+-- the in-game names and color a user usually plays with, so they can be linked in any guild they play in
+create table if not exists user_profiles
+(
+    user_id         numeric PRIMARY KEY REFERENCES users ON DELETE CASCADE, --if a user gets deleted, delete their profile
+    in_game_names   varchar(10)[] NOT NULL,                                 --lowercase, to match names case-insensitively
+    preferred_color smallint      NOT NULL                                  -- -1 for no preference
+);
+
 create index if not exists guilds_id_index ON guilds (guild_id); --query guilds by ID
 create index if not exists guilds_premium_index ON guilds (premium); --query guilds by prem status
 
@@ -67,5 +83,7 @@ create index if not exists users_games_guild_id_index ON users_games (guild_id);
 create index if not exists users_games_role_index ON users_games (player_role); --query games by win status
 create index if not exists users_games_won_index ON users_games (player_won); --query games by win status
 
+create index if not exists user_profiles_in_game_names_index on user_profiles using gin (in_game_names); --query profiles by in-game name
+
 create index if not exists game_events_game_id_index on game_events (game_id); --query for game events by the game ID
 create index if not exists game_events_user_id_index on game_events (user_id); --query for game events by the user ID
\ No newline at end of file